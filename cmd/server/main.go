@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	gamehttp "github.com/ChesS-ma/gameplay_service/internal/adapters/handler/http"
@@ -22,6 +24,7 @@ func main() {
 	// 1. Initialize Redis
 	rdb := goredis.NewClient(&goredis.Options{Addr: "localhost:6379"})
 	redisRepo := redis.NewRedisGameRepository(rdb)
+	leaderboardRepo := redis.NewRedisLeaderboardRepository(rdb)
 
 	// 2. Initialize MongoDB
 	mClient, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
@@ -29,19 +32,41 @@ func main() {
 		log.Fatal(err)
 	}
 	mongoRepo := mongodb.NewMongoArchiveRepository(mClient)
+	ratingRepo := mongodb.NewMongoRatingRepository(mClient)
 
-	// 3. Initialize Service (Injecting BOTH repos)
-	gameService := services.NewService(redisRepo, mongoRepo)
+	// 3. Initialize Service (Injecting the repos)
+	gameService := services.NewService(redisRepo, mongoRepo, ratingRepo, leaderboardRepo)
+	go gameService.RunDeadlineSweeper(context.Background(), time.Minute)
+	go gameService.RunArchiveSweeper(context.Background(), 5*time.Minute)
+
+	// Players drop off the leaderboards after LEADERBOARD_INACTIVE_DAYS without a rated game
+	inactiveDays := 30
+	if v, err := strconv.Atoi(os.Getenv("LEADERBOARD_INACTIVE_DAYS")); err == nil && v > 0 {
+		inactiveDays = v
+	}
+	leaderboardService := services.NewLeaderboardService(leaderboardRepo, mongoRepo, time.Duration(inactiveDays)*24*time.Hour)
+	go leaderboardService.RunInactivityPruner(context.Background(), time.Hour)
 
 	// 4. Initialize Handler (Injecting the game Service )
 	gameHandler := gamehttp.NewGameHandler(gameService)
 	wsHandler := gamehttp.NewWsHandler(gameService)
+	leaderboardHandler := gamehttp.NewLeaderboardHandler(leaderboardService)
 
 	// 5. Routes
 	http.HandleFunc("/games/create", gameHandler.CreateGame)
 	// WebSocket Route (The "Live" connection for playing)
 	http.HandleFunc("/ws", wsHandler.HandleWS)
 	http.HandleFunc("/games/get", gameHandler.GetGame)
+	http.HandleFunc("/games/pgn", gameHandler.GetPGN)
+	http.HandleFunc("/games/my-turn", gameHandler.GamesAwaitingMove)
+	http.HandleFunc("/games/resign", gameHandler.Resign)
+	http.HandleFunc("/games/abort", gameHandler.Abort)
+	http.HandleFunc("/games/claim-draw", gameHandler.ClaimDraw)
+	http.HandleFunc("/games/takeback", gameHandler.Takeback)
+	http.HandleFunc("/games/pause", gameHandler.Pause)
+	http.HandleFunc("/games/resume", gameHandler.Resume)
+	http.HandleFunc("/leaderboards", leaderboardHandler.GetLeaderboard)
+	http.HandleFunc("/leaderboards/rebuild", leaderboardHandler.Rebuild)
 
 	log.Println("Chess Service running on :8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
 	"github.com/ChesS-ma/gameplay_service/internal/core/ports"
 	"github.com/google/uuid" // Needed to parse IDs
@@ -24,11 +25,37 @@ type CreateGameRequest struct {
 	WhiteId  string             `json:"white_id"`
 	BlackId  string             `json:"black_id"`
 	Settings domain.TimeControl `json:"settings"`
+
+	Variant          domain.Variant `json:"variant"`           // "standard" (default) or "chess960"
+	Chess960Position *int           `json:"chess960_position"` // 0-959, random if omitted
+	StartFEN         string         `json:"start_fen"`         // Optional custom starting position
+	Rated            bool           `json:"rated"`             // Updates the players' ratings once finished
+
+	// Handicap options
+	Odds          *domain.Odds        `json:"odds,omitempty"`           // e.g. {"side": "white", "piece": "KNIGHT"}
+	WhiteSettings *domain.TimeControl `json:"white_settings,omitempty"` // Replaces Settings for White only
+	BlackSettings *domain.TimeControl `json:"black_settings,omitempty"` // Replaces Settings for Black only
 }
 
 type MakeMoveRequest struct {
+	PlayerId string            `json:"player_id"`
+	Move     string            `json:"move"`             // e.g., "e4" (SAN), "e2e4" (UCI) or "e2-e4" (LAN)
+	Format   domain.MoveFormat `json:"format,omitempty"` // "SAN", "UCI" or "LAN"; detected from Move when empty
+}
+
+type PlayerActionRequest struct {
+	PlayerId string `json:"player_id"`
+}
+
+type TakebackActionRequest struct {
 	PlayerId string `json:"player_id"`
-	Move     string `json:"move"` // Standard Algebraic Notation e.g., "e4"
+	Action   string `json:"action"` // "request", "accept" or "decline"
+}
+
+// PauseActionRequest answers both the pause and the resume flow.
+type PauseActionRequest struct {
+	PlayerId string `json:"player_id"`
+	Action   string `json:"action"` // "request", "accept" or "decline"
 }
 
 // --- Handler Methods ---
@@ -43,7 +70,20 @@ func (h *GameHandler) CreateGame(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	game, err := h.service.CreateGame(r.Context(), req.WhiteId, req.BlackId, req.Settings)
+	setup := domain.GameSetup{
+		Variant:          req.Variant,
+		Chess960Position: req.Chess960Position,
+		StartFEN:         req.StartFEN,
+		Odds:             req.Odds,
+		WhiteSettings:    req.WhiteSettings,
+		BlackSettings:    req.BlackSettings,
+		Rated:            req.Rated,
+	}
+	game, err := h.service.CreateGame(r.Context(), req.WhiteId, req.BlackId, req.Settings, setup)
+	if errors.Is(err, domain.ErrInvalidSetup) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -74,10 +114,9 @@ func (h *GameHandler) MakeMove(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 3. Call service
-	game, err := h.service.MakeMove(r.Context(), gameId, req.PlayerId, req.Move)
+	game, err := h.service.MakeMove(r.Context(), gameId, req.PlayerId, req.Move, req.Format)
 	if err != nil {
-		// We use StatusConflict or BadRequest for illegal moves
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeActionError(w, err)
 		return
 	}
 	//log.Printf("Player %s moved. Time remaining: %.2f seconds",
@@ -108,6 +147,270 @@ func (h *GameHandler) GetGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Pending offers are only shown to the player who has to answer them
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game.SeenBy(r.URL.Query().Get("player_id")))
+}
+
+func (h *GameHandler) GamesAwaitingMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	playerID := r.URL.Query().Get("player_id")
+	if playerID == "" {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	}
+
+	games, err := h.service.GamesAwaitingMove(r.Context(), playerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(games)
+}
+
+func (h *GameHandler) GetPGN(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	gameId, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	game, err := h.service.GetGame(r.Context(), gameId)
+	if err != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Write([]byte(game.PGN()))
+}
+
+func (h *GameHandler) Resign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	gameId, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid game ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req PlayerActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	game, err := h.service.Resign(r.Context(), gameId, req.PlayerId)
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game)
+}
+
+func (h *GameHandler) Abort(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	gameId, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid game ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req PlayerActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	game, err := h.service.Abort(r.Context(), gameId, req.PlayerId)
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(game)
 }
+
+func (h *GameHandler) ClaimDraw(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	gameId, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid game ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req PlayerActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	game, err := h.service.ClaimDraw(r.Context(), gameId, req.PlayerId)
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game)
+}
+
+func (h *GameHandler) Takeback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	gameId, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid game ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req TakebackActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var game *domain.Game
+	switch req.Action {
+	case "request":
+		game, err = h.service.RequestTakeback(r.Context(), gameId, req.PlayerId)
+	case "accept":
+		game, err = h.service.AcceptTakeback(r.Context(), gameId, req.PlayerId)
+	case "decline":
+		game, err = h.service.DeclineTakeback(r.Context(), gameId, req.PlayerId)
+	default:
+		http.Error(w, "Unknown takeback action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game)
+}
+
+func (h *GameHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	gameId, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid game ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req PauseActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var game *domain.Game
+	switch req.Action {
+	case "request":
+		game, err = h.service.RequestPause(r.Context(), gameId, req.PlayerId)
+	case "accept":
+		game, err = h.service.AcceptPause(r.Context(), gameId, req.PlayerId)
+	case "decline":
+		game, err = h.service.DeclinePause(r.Context(), gameId, req.PlayerId)
+	default:
+		http.Error(w, "Unknown pause action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game)
+}
+
+func (h *GameHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	gameId, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid game ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req PauseActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var game *domain.Game
+	switch req.Action {
+	case "request":
+		game, err = h.service.RequestResume(r.Context(), gameId, req.PlayerId)
+	case "accept":
+		game, err = h.service.AcceptResume(r.Context(), gameId, req.PlayerId)
+	case "decline":
+		game, err = h.service.DeclineResume(r.Context(), gameId, req.PlayerId)
+	default:
+		http.Error(w, "Unknown resume action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game)
+}
+
+// writeActionError reports a game action the service turned down. A version
+// conflict that outlasted the service's retries is a 409 the client may simply
+// try again; anything else is a 400, the action itself was rejected.
+func writeActionError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, domain.ErrVersionConflict) {
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+	"github.com/ChesS-ma/gameplay_service/internal/core/ports"
+)
+
+const defaultLeaderboardPageSize = 50
+
+type LeaderboardHandler struct {
+	service ports.LeaderboardService
+}
+
+func NewLeaderboardHandler(service ports.LeaderboardService) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		service: service,
+	}
+}
+
+// GetLeaderboard serves /leaderboards?category=blitz&page=1&page_size=50
+func (h *LeaderboardHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	category, err := domain.ParseTimeCategory(query.Get("category"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, pageSize := 1, defaultLeaderboardPageSize
+	if v := query.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid page", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("page_size"); v != "" {
+		if pageSize, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid page_size", http.StatusBadRequest)
+			return
+		}
+	}
+
+	board, err := h.service.Leaderboard(r.Context(), category, page, pageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(board)
+}
+
+// Rebuild recomputes every leaderboard from the archive.
+func (h *LeaderboardHandler) Rebuild(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.service.Rebuild(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
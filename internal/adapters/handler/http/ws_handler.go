@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
 	"github.com/ChesS-ma/gameplay_service/internal/core/ports"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -39,10 +40,46 @@ var upgrader = websocket.Upgrader{
 }
 
 func NewWsHandler(service ports.GameService) *WsHandler {
-	return &WsHandler{
+	h := &WsHandler{
 		service: service,
 		rooms:   make(map[uuid.UUID][]*Client),
 	}
+	service.Subscribe(h.handleGameEvent)
+	return h
+}
+
+// handleGameEvent pushes every change to a game to its room, whether it came
+// from a socket, an HTTP request or the service itself (e.g. a flag falling).
+func (h *WsHandler) handleGameEvent(event domain.GameEvent) {
+	game, ok := event.Payload.(*domain.Game)
+	if !ok {
+		return
+	}
+
+	switch event.Type {
+	case domain.EventGameUpdated:
+		h.broadcastGame(game)
+
+	case domain.EventGameFinished:
+		h.broadcastGame(game)
+		h.broadcastGameOver(game)
+
+	case domain.EventAbandonmentClaimable:
+		absentID, ok := game.AbandonedBy(time.Now())
+		if !ok {
+			return
+		}
+		h.offerAbandonmentClaim(game, absentID)
+	}
+}
+
+// offerAbandonmentClaim lets the opponent of an absent player know they can
+// now claim victory or call the game a draw.
+func (h *WsHandler) offerAbandonmentClaim(game *domain.Game, absentID string) {
+	opponentID, _ := game.OpponentOf(absentID)
+	h.sendToPlayer(game.ID, opponentID, "ABANDONMENT_CLAIMABLE", map[string]string{
+		"player_id": absentID,
+	})
 }
 
 // HandleWS is the main entry point for /ws?game_id=...&player_id=...
@@ -73,23 +110,38 @@ func (h *WsHandler) HandleWS(w http.ResponseWriter, r *http.Request) {
 	go h.writePump(client)
 	go h.readPump(client)
 
-	// Now that the pumps are running, we fetch and send the state
-	game, err := h.service.GetGame(context.Background(), gameID)
+	// Now that the pumps are running, we mark the player present, which sends
+	// the state to the whole room, this client included
+	game, err := h.service.SetPresence(context.Background(), gameID, playerID, true)
 	if err != nil {
-		log.Printf("Sync Error for game %s: %v", gameID, err)
-		// Optional: send an error message to the client via their channel
-		return
-	}
+		// Spectators and finished games have no presence to track, so they get the state on their own
+		game, err = h.service.GetGame(context.Background(), gameID)
+		if err != nil {
+			log.Printf("Sync Error for game %s: %v", gameID, err)
+			// Optional: send an error message to the client via their channel
+			return
+		}
+
+		gameData, _ := json.Marshal(game.SeenBy(playerID))
+		syncEvent := WsEvent{
+			Type:    "GAME_UPDATE",
+			Payload: gameData,
+		}
+		msg, _ := json.Marshal(syncEvent)
 
-	gameData, _ := json.Marshal(game)
-	syncEvent := WsEvent{
-		Type:    "GAME_UPDATE",
-		Payload: gameData,
+		// The writePump is now active and will immediately pick this up
+		h.deliver(client, msg)
 	}
-	msg, _ := json.Marshal(syncEvent)
+	h.broadcastToRoom(gameID, "PLAYER_CONNECTED", map[string]string{
+		"player_id": playerID,
+	})
 
-	// The writePump is now active and will immediately pick this up
-	client.Send <- msg
+	// A claim that became available while this player was away is offered again
+	if absentID, ok := game.AbandonedBy(time.Now()); ok && absentID != playerID {
+		if opponentID, _ := game.OpponentOf(absentID); opponentID == playerID {
+			h.offerAbandonmentClaim(game, absentID)
+		}
+	}
 }
 
 func (h *WsHandler) readPump(c *Client) {
@@ -122,27 +174,173 @@ func (h *WsHandler) readPump(c *Client) {
 			continue
 		}
 
-		if event.Type == "MOVE" {
+		// The service reports every change it makes, so GAME_UPDATE and
+		// GAME_OVER reach the room through handleGameEvent; the cases below only
+		// deal with errors and with what is meant for a single player.
+		switch event.Type {
+		case "MOVE":
+			var req struct {
+				Move   string            `json:"move"`
+				Format domain.MoveFormat `json:"format"`
+			}
+			json.Unmarshal(event.Payload, &req)
+
+			if _, err := h.service.MakeMove(context.Background(), c.GameID, c.PlayerID, req.Move, req.Format); err != nil {
+				h.sendError(c, err.Error())
+			}
+
+		case "PREMOVE":
 			var req struct {
-				Move string `json:"move"`
+				Move   string            `json:"move"`
+				Format domain.MoveFormat `json:"format"`
 			}
 			json.Unmarshal(event.Payload, &req)
 
-			game, err := h.service.MakeMove(context.Background(), c.GameID, c.PlayerID, req.Move)
+			game, err := h.service.SetPremove(context.Background(), c.GameID, c.PlayerID, req.Move, req.Format)
 			if err != nil {
 				h.sendError(c, err.Error())
 				continue
 			}
-			// Broadcast to the players that the game is updated
-			h.broadcastToRoom(c.GameID, "GAME_UPDATE", game)
+			// Only the player who queued it may know about the premove
+			h.sendToPlayer(c.GameID, c.PlayerID, "PREMOVE_SET", game.PendingPremove())
+
+		case "PREMOVE_CANCEL":
+			if _, err := h.service.CancelPremove(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+				continue
+			}
+			h.sendToPlayer(c.GameID, c.PlayerID, "PREMOVE_CANCELLED", nil)
+
+		case "RESIGN":
+			if _, err := h.service.Resign(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+			}
+
+		case "ABORT":
+			if _, err := h.service.Abort(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+			}
 
-			// 2. Check if the game is over
+		case "DRAW_OFFER":
+			game, err := h.service.OfferDraw(context.Background(), c.GameID, c.PlayerID)
+			if err != nil {
+				h.sendError(c, err.Error())
+				continue
+			}
+			// Crossing offers end the game straight away
 			if game.IsGameOver() {
-				h.broadcastToRoom(c.GameID, "GAME_OVER", map[string]interface{}{
-					"winner": game.WinnerID,
-					"reason": game.ResultReason,
-				})
+				continue
+			}
+			// Only the opponent gets to answer the offer
+			opponentID, _ := game.OpponentOf(c.PlayerID)
+			h.sendToPlayer(c.GameID, opponentID, "DRAW_OFFER", game.DrawOffer)
+
+		case "DRAW_ACCEPT":
+			if _, err := h.service.AcceptDraw(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+			}
+
+		case "DRAW_DECLINE":
+			if _, err := h.service.DeclineDraw(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+				continue
+			}
+			h.broadcastToRoom(c.GameID, "DRAW_DECLINED", map[string]string{
+				"player_id": c.PlayerID,
+			})
+
+		case "CLAIM_DRAW":
+			if _, err := h.service.ClaimDraw(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+			}
+
+		case "TAKEBACK_REQUEST":
+			game, err := h.service.RequestTakeback(context.Background(), c.GameID, c.PlayerID)
+			if err != nil {
+				h.sendError(c, err.Error())
+				continue
+			}
+			opponentID, _ := game.OpponentOf(c.PlayerID)
+			h.sendToPlayer(c.GameID, opponentID, "TAKEBACK_REQUEST", game.TakebackRequest)
+
+		case "TAKEBACK_ACCEPT":
+			if _, err := h.service.AcceptTakeback(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+			}
+
+		case "TAKEBACK_DECLINE":
+			if _, err := h.service.DeclineTakeback(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+				continue
+			}
+			h.broadcastToRoom(c.GameID, "TAKEBACK_DECLINED", map[string]string{
+				"player_id": c.PlayerID,
+			})
+
+		case "CLAIM_VICTORY", "CALL_DRAW":
+			draw := event.Type == "CALL_DRAW"
+			if _, err := h.service.ClaimAbandonment(context.Background(), c.GameID, c.PlayerID, draw); err != nil {
+				h.sendError(c, err.Error())
+			}
+
+		case "GIVE_TIME":
+			if _, err := h.service.GiveTime(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
 			}
+
+		case "PAUSE_REQUEST":
+			game, err := h.service.RequestPause(context.Background(), c.GameID, c.PlayerID)
+			if err != nil {
+				h.sendError(c, err.Error())
+				continue
+			}
+			// Crossing requests pause the game straight away
+			if game.State == domain.StatePaused {
+				continue
+			}
+			opponentID, _ := game.OpponentOf(c.PlayerID)
+			h.sendToPlayer(c.GameID, opponentID, "PAUSE_REQUEST", game.PauseRequest)
+
+		case "PAUSE_ACCEPT":
+			if _, err := h.service.AcceptPause(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+			}
+
+		case "PAUSE_DECLINE":
+			if _, err := h.service.DeclinePause(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+				continue
+			}
+			h.broadcastToRoom(c.GameID, "PAUSE_DECLINED", map[string]string{
+				"player_id": c.PlayerID,
+			})
+
+		case "RESUME_REQUEST":
+			game, err := h.service.RequestResume(context.Background(), c.GameID, c.PlayerID)
+			if err != nil {
+				h.sendError(c, err.Error())
+				continue
+			}
+			// Crossing requests resume the game straight away
+			if game.State != domain.StatePaused {
+				continue
+			}
+			opponentID, _ := game.OpponentOf(c.PlayerID)
+			h.sendToPlayer(c.GameID, opponentID, "RESUME_REQUEST", game.ResumeRequest)
+
+		case "RESUME_ACCEPT":
+			if _, err := h.service.AcceptResume(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+			}
+
+		case "RESUME_DECLINE":
+			if _, err := h.service.DeclineResume(context.Background(), c.GameID, c.PlayerID); err != nil {
+				h.sendError(c, err.Error())
+				continue
+			}
+			h.broadcastToRoom(c.GameID, "RESUME_DECLINED", map[string]string{
+				"player_id": c.PlayerID,
+			})
 		}
 	}
 }
@@ -195,21 +393,37 @@ func (h *WsHandler) registerClient(c *Client) {
 
 func (h *WsHandler) unregisterClient(c *Client) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+	removed, stillConnected := false, false
 	clients := h.rooms[c.GameID]
 	for i, client := range clients {
 		if client == c {
 			// Remove the client from the slice
-			h.rooms[c.GameID] = append(clients[:i], clients[i+1:]...)
-			// Notify the room that a specific player is gone
-			// doing this while still holding the lock for safety
-			h.broadcastToRoom(c.GameID, "PLAYER_DISCONNECTED", map[string]string{
-				"player_id": c.PlayerID,
-			})
+			clients = append(clients[:i], clients[i+1:]...)
+			h.rooms[c.GameID] = clients
+			removed = true
 			break
 		}
 	}
+	for _, client := range clients {
+		if client.PlayerID == c.PlayerID {
+			stillConnected = true // e.g. the game is still open in another tab
+		}
+	}
+	// broadcastToRoom takes the lock itself, so it has to be released first
+	h.mu.Unlock()
+
+	if !removed || stillConnected {
+		return
+	}
+	// Fails for spectators and finished games, which have no presence to track
+	_, _ = h.service.SetPresence(context.Background(), c.GameID, c.PlayerID, false)
+
+	// Notify the room that a specific player is gone
+	h.broadcastToRoom(c.GameID, "PLAYER_DISCONNECTED", map[string]string{
+		"player_id": c.PlayerID,
+	})
 }
+
 func (h *WsHandler) broadcastToRoom(gameID uuid.UUID, eventType string, payload interface{}) {
 	h.mu.RLock()
 	clients := h.rooms[gameID]
@@ -220,13 +434,73 @@ func (h *WsHandler) broadcastToRoom(gameID uuid.UUID, eventType string, payload
 	msg, _ := json.Marshal(event)
 
 	for _, client := range clients {
-		client.Send <- msg
+		h.deliver(client, msg)
 	}
 }
+
+// broadcastGame sends the game to every connection in its room, each one seeing
+// only the pending offers addressed to its player (see Game.SeenBy).
+func (h *WsHandler) broadcastGame(game *domain.Game) {
+	h.mu.RLock()
+	clients := h.rooms[game.ID]
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		data, _ := json.Marshal(game.SeenBy(client.PlayerID))
+		msg, _ := json.Marshal(WsEvent{Type: "GAME_UPDATE", Payload: data})
+		h.deliver(client, msg)
+	}
+}
+
+// sendToPlayer delivers an event only to the connections of one player in the room.
+func (h *WsHandler) sendToPlayer(gameID uuid.UUID, playerID string, eventType string, payload interface{}) {
+	h.mu.RLock()
+	clients := h.rooms[gameID]
+	h.mu.RUnlock()
+
+	data, _ := json.Marshal(payload)
+	event := WsEvent{Type: eventType, Payload: data}
+	msg, _ := json.Marshal(event)
+
+	for _, client := range clients {
+		if client.PlayerID == playerID {
+			h.deliver(client, msg)
+		}
+	}
+}
+
+// broadcastGameOver announces the result to the room once the game has finished.
+func (h *WsHandler) broadcastGameOver(game *domain.Game) {
+	if !game.IsGameOver() {
+		return
+	}
+	h.broadcastToRoom(game.ID, "GAME_OVER", map[string]interface{}{
+		"state":       game.State,
+		"outcome":     game.Outcome,
+		"termination": game.Termination,
+		"winner":      game.WinnerID,
+		"result":      game.GetResult(),
+	})
+}
+
 func (h *WsHandler) sendError(c *Client, msg string) {
 	// Ensure the payload is a valid JSON string without extra spaces
 	payload := []byte(`"` + msg + `"`)
 	event := WsEvent{Type: "ERROR", Payload: payload}
 	data, _ := json.Marshal(event)
-	c.Send <- data
+	h.deliver(c, data)
+}
+
+// deliver queues msg for a client without ever waiting on it: events are sent
+// from the service's own goroutines, which one slow browser must not hold up.
+// A client whose queue is full is disconnected, and gets the whole state again
+// when it reconnects.
+func (h *WsHandler) deliver(c *Client, msg []byte) {
+	select {
+	case c.Send <- msg:
+	default:
+		log.Printf("Player %s is not keeping up with game %s, disconnecting", c.PlayerID, c.GameID)
+		// readPump fails on the closed connection and unregisters the client
+		c.Conn.Close()
+	}
 }
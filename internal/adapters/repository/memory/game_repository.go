@@ -3,25 +3,31 @@ package memory
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
 	"github.com/google/uuid"
 	"sync"
+	"time"
 )
 
+// INMemoryGameRepository keeps its own copies of the games, so that, like a
+// real store, changes only become visible once they are saved.
 type INMemoryGameRepository struct {
-	games map[uuid.UUID]*domain.Game
-	mu    sync.RWMutex //safety lock for concurrent access
+	games   map[uuid.UUID]*domain.Game
+	evicted map[uuid.UUID]bool // Archived games on their way out
+	mu      sync.RWMutex       //safety lock for concurrent access
 }
 
 func NewInMemoryGameRepository() *INMemoryGameRepository {
 	return &INMemoryGameRepository{
-		games: make(map[uuid.UUID]*domain.Game),
+		games:   make(map[uuid.UUID]*domain.Game),
+		evicted: make(map[uuid.UUID]bool),
 	}
 }
 func (r *INMemoryGameRepository) Save(ctx context.Context, game *domain.Game) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.games[game.ID] = game
+	r.games[game.ID] = game.Clone()
 	return nil
 }
 func (r *INMemoryGameRepository) FindByID(ctx context.Context, id uuid.UUID) (*domain.Game, error) {
@@ -31,13 +37,77 @@ func (r *INMemoryGameRepository) FindByID(ctx context.Context, id uuid.UUID) (*d
 	if !exists {
 		return nil, errors.New("game not found")
 	}
-	return game, nil
+	return game.Clone(), nil
 }
 
 func (r *INMemoryGameRepository) Update(ctx context.Context, game *domain.Game) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.games[game.ID] = game
+	stored, exists := r.games[game.ID]
+	if !exists {
+		return errors.New("game not found")
+	}
+	if stored.Version != game.Version {
+		return fmt.Errorf("%w: game %s is at version %d, not %d", domain.ErrVersionConflict, game.ID, stored.Version, game.Version)
+	}
+	game.Version++
+	r.games[game.ID] = game.Clone()
+	return nil
+}
+
+func (r *INMemoryGameRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.games, id)
+	delete(r.evicted, id)
 	return nil
+}
+
+func (r *INMemoryGameRepository) Evict(ctx context.Context, id uuid.UUID, keepFor time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.evicted[id] = true
+	time.AfterFunc(keepFor, func() {
+		r.Delete(context.Background(), id)
+	})
+	return nil
+}
 
+func (r *INMemoryGameRepository) FindByPlayer(ctx context.Context, playerID string) ([]*domain.Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var games []*domain.Game
+	for _, game := range r.games {
+		if game.IsGameOver() {
+			continue
+		}
+		if game.White.UserID == playerID || game.Black.UserID == playerID {
+			games = append(games, game.Clone())
+		}
+	}
+	return games, nil
+}
+
+func (r *INMemoryGameRepository) FindActive(ctx context.Context) ([]*domain.Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var games []*domain.Game
+	for _, game := range r.games {
+		if !game.IsGameOver() {
+			games = append(games, game.Clone())
+		}
+	}
+	return games, nil
+}
+
+func (r *INMemoryGameRepository) FindFinished(ctx context.Context) ([]*domain.Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var games []*domain.Game
+	for _, game := range r.games {
+		if game.IsGameOver() && !r.evicted[game.ID] {
+			games = append(games, game.Clone())
+		}
+	}
+	return games, nil
 }
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+	"github.com/google/uuid"
+)
+
+func TestGameUpdateComparesVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryGameRepository()
+	game := &domain.Game{ID: uuid.New(), State: domain.StateInProgress}
+	if err := repo.Save(ctx, game); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	first, err := repo.FindByID(ctx, game.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	second, err := repo.FindByID(ctx, game.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+
+	first.Rated = true
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if first.Version != 1 {
+		t.Errorf("Version = %d after the update, want 1", first.Version)
+	}
+	// second was loaded before first was saved, so it must not overwrite it
+	if err := repo.Update(ctx, second); !errors.Is(err, domain.ErrVersionConflict) {
+		t.Fatalf("Update() of a stale game error = %v, want ErrVersionConflict", err)
+	}
+
+	stored, err := repo.FindByID(ctx, game.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	if stored.Version != 1 || !stored.Rated {
+		t.Errorf("stored game = version %d, Rated %v, want the first update", stored.Version, stored.Rated)
+	}
+
+	// Changing a loaded game does not touch the stored one until it is saved
+	stored.Rated = false
+	if reloaded, _ := repo.FindByID(ctx, game.ID); !reloaded.Rated {
+		t.Error("a change to a loaded game leaked into the repository")
+	}
+}
+
+func TestGameUpdateUnknownGame(t *testing.T) {
+	repo := NewInMemoryGameRepository()
+	if err := repo.Update(context.Background(), &domain.Game{ID: uuid.New()}); err == nil {
+		t.Error("Update() of a game never saved error = nil")
+	}
+}
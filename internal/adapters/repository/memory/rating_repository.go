@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+)
+
+type ratingKey struct {
+	playerID string
+	category domain.TimeCategory
+}
+
+type InMemoryRatingRepository struct {
+	ratings map[ratingKey]domain.Rating
+	mu      sync.RWMutex
+}
+
+func NewInMemoryRatingRepository() *InMemoryRatingRepository {
+	return &InMemoryRatingRepository{
+		ratings: make(map[ratingKey]domain.Rating),
+	}
+}
+
+func (r *InMemoryRatingRepository) Get(ctx context.Context, playerID string, category domain.TimeCategory) (domain.Rating, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rating, exists := r.ratings[ratingKey{playerID, category}]
+	if !exists {
+		return domain.NewRating(playerID, category), nil
+	}
+	return rating, nil
+}
+
+func (r *InMemoryRatingRepository) Save(ctx context.Context, rating domain.Rating) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := ratingKey{rating.PlayerID, rating.Category}
+	if stored := r.ratings[key]; stored.Games != rating.Games-1 {
+		return fmt.Errorf("%w: rating of %s has %d games, not %d", domain.ErrVersionConflict, rating.PlayerID, stored.Games, rating.Games-1)
+	}
+	r.ratings[key] = rating
+	return nil
+}
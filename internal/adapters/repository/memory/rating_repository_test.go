@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+)
+
+func TestRatingSaveComparesGames(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRatingRepository()
+
+	loaded, err := repo.Get(ctx, "p", domain.CategoryBlitz)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	first, second := loaded, loaded
+	first.Games++
+	second.Games++
+
+	if err := repo.Save(ctx, first); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// second was computed from the same rating, so it must not overwrite first
+	if err := repo.Save(ctx, second); !errors.Is(err, domain.ErrVersionConflict) {
+		t.Fatalf("Save() of a stale rating error = %v, want ErrVersionConflict", err)
+	}
+
+	reloaded, err := repo.Get(ctx, "p", domain.CategoryBlitz)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	reloaded.Games++
+	if err := repo.Save(ctx, reloaded); err != nil {
+		t.Errorf("Save() after reloading error = %v", err)
+	}
+}
@@ -5,17 +5,22 @@ import (
 	"time"
 
 	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoArchiveRepository struct {
 	collection *mongo.Collection
+	aborted    *mongo.Collection // Aborted games are kept apart so they never mix with real results
 }
 
 func NewMongoArchiveRepository(client *mongo.Client) *MongoArchiveRepository {
+	db := client.Database("chessma")
 	return &MongoArchiveRepository{
-		// This will automatically create the 'chessma' db and 'archives' collection if they don't exist
-		collection: client.Database("chessma").Collection("archives"),
+		// This will automatically create the 'chessma' db and the collections if they don't exist
+		collection: db.Collection("archives"),
+		aborted:    db.Collection("aborted_games"),
 	}
 }
 
@@ -28,21 +33,143 @@ func (r *MongoArchiveRepository) Archive(ctx context.Context, game *domain.Game)
 	// 2. Map the domain object to a BSON-friendly structure
 	// We do this explicitly to control exactly how the history is stored
 	doc := map[string]interface{}{
-		"_id":       game.ID.String(), // Using ID string as the MongoDB Primary Key
-		"white_id":  game.White.UserID,
-		"black_id":  game.Black.UserID,
-		"board_fen": game.GetFEN(),
-		"history":   game.History,
-		"result":    game.GetResult(),
-		"settings": map[string]interface{}{
-			"initial_time": game.Settings.InitialTime,
-			"increment":    game.Settings.Increment,
-		},
+		"_id":         game.ID.String(), // Using ID string as the MongoDB Primary Key
+		"white_id":    game.White.UserID,
+		"black_id":    game.Black.UserID,
+		"board_fen":   game.GetFEN(),
+		"variant":     game.Variant,
+		"start_fen":   game.StartFEN,
+		"history":     game.History,
+		"pgn":         game.PGN(),
+		"result":      game.GetResult(),
+		"state":       game.State,
+		"outcome":     game.Outcome,
+		"termination": game.Termination,
+		"winner_id":   game.WinnerID,
+		"settings":    settingsDoc(game.Settings),
+		"handicap":    game.IsHandicap(), // Odds games are left out of stats
+		"rated":       game.Rated,
 		"created_at":  game.CreatedAt,
-		"archived_at": time.Now(),
+		"finished_at": game.UpdatedAt, // Unlike the archiving time, the same however often the game is archived
 	}
 
-	// 3. Execute the insert
-	_, err := r.collection.InsertOne(archiveCtx, doc)
+	if game.Chess960Position != nil {
+		doc["chess960_position"] = *game.Chess960Position
+	}
+	if game.Ratings != nil {
+		doc["ratings"] = map[string]interface{}{
+			"category": game.Ratings.Category,
+			"white":    ratingChangeDoc(game.Ratings.White),
+			"black":    ratingChangeDoc(game.Ratings.Black),
+		}
+	}
+	if game.Odds != nil {
+		doc["odds"] = map[string]interface{}{
+			"side":  game.Odds.Side,
+			"piece": game.Odds.Piece,
+		}
+	}
+	if game.White.TimeControl != nil {
+		doc["white_settings"] = settingsDoc(*game.White.TimeControl)
+	}
+	if game.Black.TimeControl != nil {
+		doc["black_settings"] = settingsDoc(*game.Black.TimeControl)
+	}
+
+	// 3. Upsert, so archiving a game again (e.g. a retry after a timeout that did go through) is harmless
+	collection := r.collection
+	if game.State == domain.StateAborted {
+		collection = r.aborted
+	}
+	id := doc["_id"]
+	delete(doc, "_id")
+	update := bson.M{
+		"$set":         doc,
+		"$setOnInsert": bson.M{"archived_at": time.Now()}, // When the game was first archived
+	}
+	_, err := collection.UpdateOne(archiveCtx, bson.M{"_id": id}, update, options.Update().SetUpsert(true))
 	return err
 }
+
+// settingsDoc maps a time control to the BSON-friendly structure it is archived as.
+func settingsDoc(tc domain.TimeControl) map[string]interface{} {
+	settings := map[string]interface{}{
+		"initial_time": tc.InitialTime,
+		"increment":    tc.Increment,
+		"mode":         tc.Mode,
+		"delay":        tc.Delay,
+	}
+	if tc.IsCorrespondence() {
+		settings["days_per_move"] = tc.DaysPerMove
+	}
+	if len(tc.Stages) > 0 {
+		stages := make([]map[string]interface{}, len(tc.Stages))
+		for i, st := range tc.Stages {
+			stages[i] = map[string]interface{}{
+				"moves":     st.Moves,
+				"time":      st.Time,
+				"increment": st.Increment,
+			}
+		}
+		settings["stages"] = stages
+	}
+	return settings
+}
+
+func ratingChangeDoc(c domain.RatingChange) map[string]interface{} {
+	return map[string]interface{}{
+		"player_id": c.PlayerID,
+		"before":    c.Before,
+		"after":     c.After,
+		"delta":     c.Delta,
+		"rd_after":  c.RDAfter,
+	}
+}
+
+func (r *MongoArchiveRepository) LatestRatings(ctx context.Context, category domain.TimeCategory, activeSince time.Time) ([]domain.Rating, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// Both sides of every rated game in the category, latest finished first,
+	// keeping the rating each player's latest game left them with. The order
+	// games were archived in says nothing, as the sweeper may archive late.
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"ratings.category": category, "finished_at": bson.M{"$gte": activeSince}}}},
+		{{Key: "$project", Value: bson.M{"finished_at": 1, "side": bson.A{"$ratings.white", "$ratings.black"}}}},
+		{{Key: "$unwind", Value: "$side"}},
+		{{Key: "$sort", Value: bson.M{"finished_at": -1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$side.player_id",
+			"rating":      bson.M{"$first": "$side.after"},
+			"rd":          bson.M{"$first": "$side.rd_after"},
+			"last_played": bson.M{"$first": "$finished_at"},
+		}}},
+	}
+	cursor, err := r.collection.Aggregate(queryCtx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(queryCtx)
+
+	var rows []struct {
+		PlayerID   string    `bson:"_id"`
+		Rating     float64   `bson:"rating"`
+		RD         float64   `bson:"rd"`
+		LastPlayed time.Time `bson:"last_played"`
+	}
+	if err := cursor.All(queryCtx, &rows); err != nil {
+		return nil, err
+	}
+
+	ratings := make([]domain.Rating, len(rows))
+	for i, row := range rows {
+		ratings[i] = domain.Rating{
+			PlayerID:  row.PlayerID,
+			Category:  category,
+			Rating:    row.Rating,
+			RD:        row.RD,
+			UpdatedAt: row.LastPlayed,
+		}
+	}
+	return ratings, nil
+}
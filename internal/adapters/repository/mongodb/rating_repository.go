@@ -0,0 +1,97 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoRatingRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoRatingRepository(client *mongo.Client) *MongoRatingRepository {
+	return &MongoRatingRepository{
+		collection: client.Database("chessma").Collection("ratings"),
+	}
+}
+
+// ratingDoc is how a rating is stored: one document per player and category.
+type ratingDoc struct {
+	ID         string              `bson:"_id"`
+	PlayerID   string              `bson:"player_id"`
+	Category   domain.TimeCategory `bson:"category"`
+	Rating     float64             `bson:"rating"`
+	RD         float64             `bson:"rd"`
+	Volatility float64             `bson:"volatility"`
+	Games      int                 `bson:"games"`
+	UpdatedAt  time.Time           `bson:"updated_at"`
+}
+
+func ratingID(playerID string, category domain.TimeCategory) string {
+	return playerID + ":" + string(category)
+}
+
+func (r *MongoRatingRepository) Get(ctx context.Context, playerID string, category domain.TimeCategory) (domain.Rating, error) {
+	findCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var doc ratingDoc
+	err := r.collection.FindOne(findCtx, bson.M{"_id": ratingID(playerID, category)}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.NewRating(playerID, category), nil
+	}
+	if err != nil {
+		return domain.Rating{}, err
+	}
+	return domain.Rating{
+		PlayerID:   doc.PlayerID,
+		Category:   doc.Category,
+		Rating:     doc.Rating,
+		RD:         doc.RD,
+		Volatility: doc.Volatility,
+		Games:      doc.Games,
+		UpdatedAt:  doc.UpdatedAt,
+	}, nil
+}
+
+func (r *MongoRatingRepository) Save(ctx context.Context, rating domain.Rating) error {
+	saveCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	doc := ratingDoc{
+		ID:         ratingID(rating.PlayerID, rating.Category),
+		PlayerID:   rating.PlayerID,
+		Category:   rating.Category,
+		Rating:     rating.Rating,
+		RD:         rating.RD,
+		Volatility: rating.Volatility,
+		Games:      rating.Games,
+		UpdatedAt:  rating.UpdatedAt,
+	}
+	conflict := fmt.Errorf("%w: rating of %s changed while being saved", domain.ErrVersionConflict, rating.PlayerID)
+
+	// A first rated game creates the document, which fails if someone else just did
+	if rating.Games == 1 {
+		_, err := r.collection.InsertOne(saveCtx, doc)
+		if mongo.IsDuplicateKeyError(err) {
+			return conflict
+		}
+		return err
+	}
+
+	// Otherwise the document is only replaced if it is the one the rating was computed from
+	res, err := r.collection.ReplaceOne(saveCtx, bson.M{"_id": doc.ID, "games": rating.Games - 1}, doc)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return conflict
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
@@ -10,40 +12,205 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	// activeKey is a set of the IDs of all games that are not over yet.
+	activeKey = "games:active"
+	// finishedKey is a set of the IDs of the games that are over but not archived yet.
+	finishedKey = "games:finished"
+)
+
 type RedisGameRepository struct {
-	client *redis.Client
+	client  *redis.Client
+	engines *domain.EngineCache // Saves replaying the whole history on every load
 }
 
 func NewRedisGameRepository(client *redis.Client) *RedisGameRepository {
-	return &RedisGameRepository{client: client}
+	return &RedisGameRepository{
+		client:  client,
+		engines: domain.NewEngineCache(),
+	}
 }
 
-// Internal wrapper to save the FEN since the engine field is private
+// Internal wrapper to save the FEN since the engine field is private, and the
+// premove, which the game keeps out of its own JSON
 type redisGameModel struct {
 	*domain.Game
-	FEN string `json:"fen"`
+	FEN     string          `json:"fen"`
+	Premove *domain.Premove `json:"premove,omitempty"`
+
+	// Games saved before they had a State only said whether and why they ended
+	LegacyFinished bool   `json:"is_finished,omitempty"`
+	LegacyReason   string `json:"result_reason,omitempty"`
+}
+
+func gameKey(id uuid.UUID) string {
+	return "game:" + id.String()
+}
+
+// playerGamesKey is a set of the IDs of a player's active games.
+func playerGamesKey(playerID string) string {
+	return "player:" + playerID + ":games"
 }
 
 func (r *RedisGameRepository) Save(ctx context.Context, game *domain.Game) error {
-	data, _ := json.Marshal(redisGameModel{Game: game, FEN: game.GetFEN()})
-	return r.client.Set(ctx, "game:"+game.ID.String(), data, 24*time.Hour).Err()
+	pipe := r.client.TxPipeline()
+	r.write(ctx, pipe, game)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	r.engines.Store(game)
+	return nil
+}
+
+// write queues the commands that store game and keep the index sets in step.
+func (r *RedisGameRepository) write(ctx context.Context, pipe redis.Pipeliner, game *domain.Game) {
+	data, _ := json.Marshal(redisGameModel{Game: game, FEN: game.GetFEN(), Premove: game.PendingPremove()})
+
+	// Live games expire after a day; an active correspondence game can
+	// legitimately sit for days between moves, and an adjourned game until
+	// the players come back, so those are kept until they end. A finished
+	// game is kept until it has been archived and evicted.
+	ttl := 24 * time.Hour
+	if game.IsGameOver() || game.Settings.IsCorrespondence() || game.State == domain.StatePaused {
+		ttl = 0
+	}
+
+	id := game.ID.String()
+	pipe.Set(ctx, gameKey(game.ID), data, ttl)
+	if game.IsGameOver() {
+		pipe.SRem(ctx, playerGamesKey(game.White.UserID), id)
+		pipe.SRem(ctx, playerGamesKey(game.Black.UserID), id)
+		pipe.SRem(ctx, activeKey, id)
+		pipe.SAdd(ctx, finishedKey, id)
+	} else {
+		pipe.SAdd(ctx, playerGamesKey(game.White.UserID), id)
+		pipe.SAdd(ctx, playerGamesKey(game.Black.UserID), id)
+		pipe.SAdd(ctx, activeKey, id)
+	}
 }
 
 func (r *RedisGameRepository) FindByID(ctx context.Context, id uuid.UUID) (*domain.Game, error) {
-	data, err := r.client.Get(ctx, "game:"+id.String()).Bytes()
+	data, err := r.client.Get(ctx, gameKey(id)).Bytes()
 	if err != nil {
 		return nil, err
 	}
 	var model redisGameModel
-	json.Unmarshal(data, &model)
-	model.Game.RehydrateEngine(model.FEN)
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, err
+	}
+	if model.Game.State == "" && model.LegacyFinished && model.LegacyReason == string(domain.TerminationTimeout) {
+		// A flag is the one result Rehydrate cannot read off the board
+		model.Game.Outcome = domain.OutcomeBlackWins
+		if model.Game.WinnerID == model.Game.White.UserID {
+			model.Game.Outcome = domain.OutcomeWhiteWins
+		}
+		model.Game.Termination = domain.TerminationTimeout
+	}
+	if err := model.Game.Rehydrate(r.engines); err != nil {
+		return nil, err
+	}
+	model.Game.RestorePremove(model.Premove)
 	return model.Game, nil
 }
 
+// Update is a compare-and-set on the game's version: the key is WATCHed, so
+// the write is dropped if anyone else touches the game before it commits.
 func (r *RedisGameRepository) Update(ctx context.Context, game *domain.Game) error {
-	return r.Save(ctx, game)
+	key := gameKey(game.ID)
+	expected := game.Version
+
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err != nil {
+			return err
+		}
+		var stored struct {
+			Version int64 `json:"version"`
+		}
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return err
+		}
+		if stored.Version != expected {
+			return fmt.Errorf("%w: game %s is at version %d, not %d", domain.ErrVersionConflict, game.ID, stored.Version, expected)
+		}
+
+		game.Version = expected + 1
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			r.write(ctx, pipe, game)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		// Changed between our read and the write, we cannot tell to which version
+		err = fmt.Errorf("%w: game %s changed while being updated", domain.ErrVersionConflict, game.ID)
+	}
+	if err != nil {
+		game.Version = expected
+		return err
+	}
+
+	r.engines.Store(game)
+	return nil
 }
 
 func (r *RedisGameRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.client.Del(ctx, "game:"+id.String()).Err()
+	r.engines.Forget(id)
+	// Player sets are cleaned up lazily by findAll, as we do not know the players here
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, gameKey(id))
+	pipe.SRem(ctx, activeKey, id.String())
+	pipe.SRem(ctx, finishedKey, id.String())
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisGameRepository) Evict(ctx context.Context, id uuid.UUID, keepFor time.Duration) error {
+	r.engines.Forget(id)
+	pipe := r.client.TxPipeline()
+	pipe.Expire(ctx, gameKey(id), keepFor)
+	pipe.SRem(ctx, finishedKey, id.String())
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisGameRepository) FindByPlayer(ctx context.Context, playerID string) ([]*domain.Game, error) {
+	return r.findAll(ctx, playerGamesKey(playerID))
+}
+
+func (r *RedisGameRepository) FindActive(ctx context.Context) ([]*domain.Game, error) {
+	return r.findAll(ctx, activeKey)
+}
+
+func (r *RedisGameRepository) FindFinished(ctx context.Context) ([]*domain.Game, error) {
+	return r.findAll(ctx, finishedKey)
+}
+
+// findAll loads every game listed in an index set, dropping the IDs of games
+// that have expired or been deleted in the meantime.
+func (r *RedisGameRepository) findAll(ctx context.Context, setKey string) ([]*domain.Game, error) {
+	ids, err := r.client.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	games := make([]*domain.Game, 0, len(ids))
+	for _, idStr := range ids {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			r.client.SRem(ctx, setKey, idStr)
+			continue
+		}
+		game, err := r.FindByID(ctx, id)
+		if errors.Is(err, redis.Nil) {
+			r.client.SRem(ctx, setKey, idStr)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, nil
 }
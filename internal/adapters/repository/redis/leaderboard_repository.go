@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisLeaderboardRepository struct {
+	client *redis.Client
+}
+
+func NewRedisLeaderboardRepository(client *redis.Client) *RedisLeaderboardRepository {
+	return &RedisLeaderboardRepository{client: client}
+}
+
+// leaderboardKey is a sorted set of a category's players, scored by rating.
+func leaderboardKey(category domain.TimeCategory) string {
+	return "leaderboard:" + string(category)
+}
+
+// leaderboardActivityKey is a sorted set of the same players, scored by the
+// Unix time of their last rated game.
+func leaderboardActivityKey(category domain.TimeCategory) string {
+	return "leaderboard:" + string(category) + ":active"
+}
+
+func (r *RedisLeaderboardRepository) Record(ctx context.Context, ratings ...domain.Rating) error {
+	pipe := r.client.TxPipeline()
+	for _, rating := range ratings {
+		addRating(ctx, pipe, rating)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisLeaderboardRepository) Page(ctx context.Context, category domain.TimeCategory, offset, limit int) ([]domain.LeaderboardEntry, int, error) {
+	pipe := r.client.TxPipeline()
+	rangeCmd := pipe.ZRevRangeWithScores(ctx, leaderboardKey(category), int64(offset), int64(offset+limit-1))
+	countCmd := pipe.ZCard(ctx, leaderboardKey(category))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]domain.LeaderboardEntry, 0, len(rangeCmd.Val()))
+	for i, z := range rangeCmd.Val() {
+		entries = append(entries, domain.LeaderboardEntry{
+			Rank:     offset + i + 1,
+			PlayerID: z.Member.(string),
+			Rating:   z.Score,
+		})
+	}
+	return entries, int(countCmd.Val()), nil
+}
+
+func (r *RedisLeaderboardRepository) RemoveInactive(ctx context.Context, category domain.TimeCategory, before time.Time) error {
+	cutoff := "(" + strconv.FormatInt(before.Unix(), 10) // Exclusive: active at the cutoff stays
+	inactive, err := r.client.ZRangeByScore(ctx, leaderboardActivityKey(category), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: cutoff,
+	}).Result()
+	if err != nil || len(inactive) == 0 {
+		return err
+	}
+
+	members := make([]interface{}, len(inactive))
+	for i, playerID := range inactive {
+		members[i] = playerID
+	}
+	pipe := r.client.TxPipeline()
+	pipe.ZRem(ctx, leaderboardKey(category), members...)
+	pipe.ZRem(ctx, leaderboardActivityKey(category), members...)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisLeaderboardRepository) Replace(ctx context.Context, category domain.TimeCategory, ratings []domain.Rating) error {
+	// In one transaction, so readers never see a half-built leaderboard
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, leaderboardKey(category), leaderboardActivityKey(category))
+	for _, rating := range ratings {
+		addRating(ctx, pipe, rating)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func addRating(ctx context.Context, pipe redis.Pipeliner, rating domain.Rating) {
+	pipe.ZAdd(ctx, leaderboardKey(rating.Category), redis.Z{Score: rating.Rating, Member: rating.PlayerID})
+	pipe.ZAdd(ctx, leaderboardActivityKey(rating.Category), redis.Z{Score: float64(rating.UpdatedAt.Unix()), Member: rating.PlayerID})
+}
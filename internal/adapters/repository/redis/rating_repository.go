@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisRatingRepository struct {
+	client *redis.Client
+}
+
+func NewRedisRatingRepository(client *redis.Client) *RedisRatingRepository {
+	return &RedisRatingRepository{client: client}
+}
+
+func ratingKey(playerID string, category domain.TimeCategory) string {
+	return "rating:" + playerID + ":" + string(category)
+}
+
+func (r *RedisRatingRepository) Get(ctx context.Context, playerID string, category domain.TimeCategory) (domain.Rating, error) {
+	data, err := r.client.Get(ctx, ratingKey(playerID, category)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return domain.NewRating(playerID, category), nil
+	}
+	if err != nil {
+		return domain.Rating{}, err
+	}
+	var rating domain.Rating
+	if err := json.Unmarshal(data, &rating); err != nil {
+		return domain.Rating{}, err
+	}
+	return rating, nil
+}
+
+// Save is a compare-and-set on the number of games: the key is WATCHed, so
+// the write is dropped if anyone else rates the player before it commits.
+func (r *RedisRatingRepository) Save(ctx context.Context, rating domain.Rating) error {
+	key := ratingKey(rating.PlayerID, rating.Category)
+	data, err := json.Marshal(rating)
+	if err != nil {
+		return err
+	}
+
+	err = r.client.Watch(ctx, func(tx *redis.Tx) error {
+		var stored domain.Rating // A player without a rating yet has played no games
+		current, err := tx.Get(ctx, key).Bytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if err == nil {
+			if err := json.Unmarshal(current, &stored); err != nil {
+				return err
+			}
+		}
+		if stored.Games != rating.Games-1 {
+			return fmt.Errorf("%w: rating of %s has %d games, not %d", domain.ErrVersionConflict, rating.PlayerID, stored.Games, rating.Games-1)
+		}
+
+		// Ratings never expire
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		return fmt.Errorf("%w: rating of %s changed while being saved", domain.ErrVersionConflict, rating.PlayerID)
+	}
+	return err
+}
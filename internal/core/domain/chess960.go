@@ -0,0 +1,409 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// The chess engine only knows standard castling (king on e1, rooks in the
+// corners). For Chess960 the engine is therefore kept without castling rights
+// and castling moves are played here: the king and rook are placed by hand
+// and the engine restarts from the resulting position. Castling can never be
+// undone, so no earlier position can repeat and repetition rules still hold.
+
+// knightPlacements lists, for the knight index of a Chess960 position number,
+// the indexes of the two knights among the five squares left after the
+// bishops and the queen are placed.
+var knightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// chess960BackRank returns White's back rank for Chess960 position number n
+// (0-959, 518 being the standard setup), e.g. "RNBQKBNR".
+func chess960BackRank(n int) (string, error) {
+	if n < 0 || n > 959 {
+		return "", errors.New("chess960 position must be between 0 and 959")
+	}
+
+	var rank [8]byte
+	rank[(n%4)*2+1] = 'B' // light-squared bishop: b, d, f or h
+	n /= 4
+	rank[(n%4)*2] = 'B' // dark-squared bishop: a, c, e or g
+	n /= 4
+
+	// placeOnEmpty puts piece on the i-th still empty square
+	placeOnEmpty := func(piece byte, i int) {
+		for file := range rank {
+			if rank[file] != 0 {
+				continue
+			}
+			if i == 0 {
+				rank[file] = piece
+				return
+			}
+			i--
+		}
+	}
+
+	placeOnEmpty('Q', n%6)
+	n /= 6
+
+	knights := knightPlacements[n]
+	// Place the second knight first so the first index is not shifted
+	placeOnEmpty('N', knights[1])
+	placeOnEmpty('N', knights[0])
+
+	// The king always sits between the two rooks
+	placeOnEmpty('R', 0)
+	placeOnEmpty('K', 0)
+	placeOnEmpty('R', 0)
+	return string(rank[:]), nil
+}
+
+// chess960FEN returns the starting FEN of Chess960 position number n, with
+// castling rights in Shredder-FEN form (rook files, e.g. "HAha").
+func chess960FEN(n int) (string, error) {
+	backRank, err := chess960BackRank(n)
+	if err != nil {
+		return "", err
+	}
+
+	queenRook := strings.IndexByte(backRank, 'R')
+	kingRook := strings.LastIndexByte(backRank, 'R')
+	castling := string(rune('A'+kingRook)) + string(rune('A'+queenRook))
+	castling += strings.ToLower(castling)
+
+	return fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w %s - 0 1",
+		strings.ToLower(backRank), backRank, castling), nil
+}
+
+// castling960 holds the Chess960 castling rights still available: the file of
+// each castling rook (0 = a-file) per color and side, or -1 once lost.
+type castling960 struct {
+	kingFile [2]int    // indexed by colorIndex
+	rooks    [2][2]int // [colorIndex][sideIndex]
+}
+
+const (
+	kingSide  = 0
+	queenSide = 1
+)
+
+func colorIndex(c chess.Color) int {
+	if c == chess.Black {
+		return 1
+	}
+	return 0
+}
+
+func backRankOf(c chess.Color) int {
+	if c == chess.Black {
+		return 7
+	}
+	return 0
+}
+
+// parseCastling960 reads Shredder-FEN castling rights ("HAha", "-", ...)
+// against the kings on board.
+func parseCastling960(field string, board map[chess.Square]chess.Piece) (*castling960, error) {
+	c := &castling960{rooks: [2][2]int{{-1, -1}, {-1, -1}}}
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		c.kingFile[colorIndex(color)] = -1
+		for file := 0; file < 8; file++ {
+			p := board[square(file, backRankOf(color))]
+			if p.Type() == chess.King && p.Color() == color {
+				c.kingFile[colorIndex(color)] = file
+			}
+		}
+	}
+
+	if field == "-" {
+		return c, nil
+	}
+	for _, r := range field {
+		color, file := chess.White, int(r-'A')
+		if r >= 'a' && r <= 'h' {
+			color, file = chess.Black, int(r-'a')
+		}
+		ci := colorIndex(color)
+		if file < 0 || file > 7 || c.kingFile[ci] < 0 || file == c.kingFile[ci] {
+			return nil, fmt.Errorf("invalid chess960 castling rights %q", field)
+		}
+		// The right has to name a rook of that color actually on its back rank
+		if p := board[square(file, backRankOf(color))]; p.Type() != chess.Rook || p.Color() != color {
+			return nil, fmt.Errorf("chess960 castling right %q has no rook to castle with", r)
+		}
+		side := kingSide
+		if file < c.kingFile[ci] {
+			side = queenSide
+		}
+		if c.rooks[ci][side] >= 0 {
+			return nil, fmt.Errorf("invalid chess960 castling rights %q", field)
+		}
+		c.rooks[ci][side] = file
+	}
+	return c, nil
+}
+
+// String renders the rights in Shredder-FEN form.
+func (c *castling960) String() string {
+	var b strings.Builder
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		base := 'A'
+		if color == chess.Black {
+			base = 'a'
+		}
+		for _, side := range []int{kingSide, queenSide} {
+			if file := c.rooks[colorIndex(color)][side]; file >= 0 {
+				b.WriteRune(base + rune(file))
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+// update drops every right whose king or rook has left its starting square.
+func (c *castling960) update(board map[chess.Square]chess.Piece) {
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		ci, rank := colorIndex(color), backRankOf(color)
+		if p := board[square(c.kingFile[ci], rank)]; c.kingFile[ci] < 0 || p.Type() != chess.King || p.Color() != color {
+			c.rooks[ci] = [2]int{-1, -1}
+			continue
+		}
+		for side, file := range c.rooks[ci] {
+			if file < 0 {
+				continue
+			}
+			if p := board[square(file, rank)]; p.Type() != chess.Rook || p.Color() != color {
+				c.rooks[ci][side] = -1
+			}
+		}
+	}
+}
+
+// castleSide recognises castling notation ("O-O", "0-0-0+", ...).
+func castleSide(notation string) (int, bool) {
+	n := strings.TrimRight(strings.ReplaceAll(notation, "0", "O"), "+#")
+	switch n {
+	case "O-O":
+		return kingSide, true
+	case "O-O-O":
+		return queenSide, true
+	}
+	return 0, false
+}
+
+// castle960 plays a Chess960 castling move for the side to move and restarts
+// the engine from the resulting position.
+func (g *Game) castle960(side int) error {
+	pos := g.internalGame.Position()
+	color := pos.Turn()
+	ci, rank := colorIndex(color), backRankOf(color)
+
+	kingFrom, rookFrom := g.castling.kingFile[ci], g.castling.rooks[ci][side]
+	if rookFrom < 0 {
+		return errors.New("castling is not available")
+	}
+	kingTo, rookTo := 6, 5 // g- and f-file
+	if side == queenSide {
+		kingTo, rookTo = 2, 3 // c- and d-file
+	}
+
+	board := pos.Board().SquareMap()
+
+	// Every square the king or rook crosses or lands on must be empty,
+	// apart from the castling king and rook themselves
+	lo, hi := min(kingFrom, kingTo, rookFrom, rookTo), max(kingFrom, kingTo, rookFrom, rookTo)
+	for file := lo; file <= hi; file++ {
+		if file == kingFrom || file == rookFrom {
+			continue
+		}
+		if _, occupied := board[square(file, rank)]; occupied {
+			return errors.New("castling path is blocked")
+		}
+	}
+
+	// The king may not leave, cross or land on an attacked square
+	withoutKing := copyBoard(board)
+	delete(withoutKing, square(kingFrom, rank))
+	step := 1
+	if kingTo < kingFrom {
+		step = -1
+	}
+	for file := kingFrom; ; file += step {
+		if squareAttacked(withoutKing, square(file, rank), color.Other()) {
+			return errors.New("cannot castle out of, through or into check")
+		}
+		if file == kingTo {
+			break
+		}
+	}
+
+	after := copyBoard(board)
+	king, rook := after[square(kingFrom, rank)], after[square(rookFrom, rank)]
+	delete(after, square(kingFrom, rank))
+	delete(after, square(rookFrom, rank))
+	after[square(kingTo, rank)] = king
+	after[square(rookTo, rank)] = rook
+	// Moving the rook away may expose the king on its new square
+	if squareAttacked(after, square(kingTo, rank), color.Other()) {
+		return errors.New("cannot castle out of, through or into check")
+	}
+
+	fields := strings.Fields(g.internalGame.FEN())
+	halfMoves, _ := strconv.Atoi(fields[4])
+	fullMoves, _ := strconv.Atoi(fields[5])
+	turn := "b"
+	if color == chess.Black {
+		turn = "w"
+		fullMoves++
+	}
+	fen := fmt.Sprintf("%s %s - - %d %d", boardFEN(after), turn, halfMoves+1, fullMoves)
+
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return err
+	}
+	g.internalGame = chess.NewGame(opt)
+	return nil
+}
+
+// squareAttacked reports whether any piece of color by attacks sq.
+func squareAttacked(board map[chess.Square]chess.Piece, sq chess.Square, by chess.Color) bool {
+	file, rank := int(sq)%8, int(sq)/8
+
+	pieceAt := func(f, r int) (chess.Piece, bool) {
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			return chess.NoPiece, false
+		}
+		p, ok := board[square(f, r)]
+		return p, ok
+	}
+	is := func(f, r int, types ...chess.PieceType) bool {
+		p, ok := pieceAt(f, r)
+		if !ok || p.Color() != by {
+			return false
+		}
+		for _, t := range types {
+			if p.Type() == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Pawns attack diagonally forward, so look one rank behind sq
+	pawnRank := rank - 1
+	if by == chess.Black {
+		pawnRank = rank + 1
+	}
+	if is(file-1, pawnRank, chess.Pawn) || is(file+1, pawnRank, chess.Pawn) {
+		return true
+	}
+
+	for _, d := range [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}} {
+		if is(file+d[0], rank+d[1], chess.Knight) {
+			return true
+		}
+	}
+	for _, d := range [][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}} {
+		if is(file+d[0], rank+d[1], chess.King) {
+			return true
+		}
+	}
+
+	slide := func(dirs [][2]int, types ...chess.PieceType) bool {
+		for _, d := range dirs {
+			for f, r := file+d[0], rank+d[1]; f >= 0 && f <= 7 && r >= 0 && r <= 7; f, r = f+d[0], r+d[1] {
+				if _, occupied := pieceAt(f, r); occupied {
+					if is(f, r, types...) {
+						return true
+					}
+					break
+				}
+			}
+		}
+		return false
+	}
+	return slide([][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}, chess.Rook, chess.Queen) ||
+		slide([][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}, chess.Bishop, chess.Queen)
+}
+
+// boardFEN renders the piece placement field of a FEN.
+func boardFEN(board map[chess.Square]chess.Piece) string {
+	var b strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			p, ok := board[square(file, rank)]
+			if !ok || p == chess.NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				b.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			b.WriteString(pieceFEN(p))
+		}
+		if empty > 0 {
+			b.WriteString(strconv.Itoa(empty))
+		}
+		if rank > 0 {
+			b.WriteByte('/')
+		}
+	}
+	return b.String()
+}
+
+// pieceFEN returns the FEN letter of a piece (upper case for White).
+func pieceFEN(p chess.Piece) string {
+	var letter string
+	switch p.Type() {
+	case chess.King:
+		letter = "k"
+	case chess.Queen:
+		letter = "q"
+	case chess.Rook:
+		letter = "r"
+	case chess.Bishop:
+		letter = "b"
+	case chess.Knight:
+		letter = "n"
+	case chess.Pawn:
+		letter = "p"
+	}
+	if p.Color() == chess.White {
+		return strings.ToUpper(letter)
+	}
+	return letter
+}
+
+func square(file, rank int) chess.Square {
+	return chess.Square(rank*8 + file)
+}
+
+func copyBoard(board map[chess.Square]chess.Piece) map[chess.Square]chess.Piece {
+	c := make(map[chess.Square]chess.Piece, len(board))
+	for sq, p := range board {
+		c[sq] = p
+	}
+	return c
+}
+
+// withCastlingField replaces the castling rights field of a FEN.
+func withCastlingField(fen, castling string) string {
+	fields := strings.Fields(fen)
+	if len(fields) < 3 {
+		return fen
+	}
+	fields[2] = castling
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChess960BackRank(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "BBQNNRKR"},
+		{518, "RNBQKBNR"},
+		{959, "RKRNNQBB"},
+	}
+	for _, tt := range tests {
+		got, err := chess960BackRank(tt.n)
+		if err != nil || got != tt.want {
+			t.Errorf("chess960BackRank(%d) = %q, %v, want %q", tt.n, got, err, tt.want)
+		}
+	}
+
+	for _, n := range []int{-1, 960} {
+		if _, err := chess960BackRank(n); err == nil {
+			t.Errorf("chess960BackRank(%d) error = nil, want an error", n)
+		}
+	}
+}
+
+func TestChess960BackRankAllPositions(t *testing.T) {
+	seen := make(map[string]int)
+	for n := 0; n < 960; n++ {
+		rank, err := chess960BackRank(n)
+		if err != nil {
+			t.Fatalf("chess960BackRank(%d) error = %v", n, err)
+		}
+		if prev, ok := seen[rank]; ok {
+			t.Fatalf("positions %d and %d are both %q", prev, n, rank)
+		}
+		seen[rank] = n
+
+		if got := sortedPieces(rank); got != "BBKNNQRR" {
+			t.Fatalf("position %d %q has pieces %q", n, rank, got)
+		}
+		bishop, otherBishop := strings.IndexByte(rank, 'B'), strings.LastIndexByte(rank, 'B')
+		if bishop%2 == otherBishop%2 {
+			t.Errorf("position %d %q has both bishops on one color", n, rank)
+		}
+		king := strings.IndexByte(rank, 'K')
+		if !(strings.IndexByte(rank, 'R') < king && king < strings.LastIndexByte(rank, 'R')) {
+			t.Errorf("position %d %q does not have the king between the rooks", n, rank)
+		}
+	}
+}
+
+func sortedPieces(rank string) string {
+	pieces := strings.Split(rank, "")
+	sort.Strings(pieces)
+	return strings.Join(pieces, "")
+}
+
+func TestChess960FEN(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{518, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1"},
+		{0, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1"},
+	}
+	for _, tt := range tests {
+		if got, err := chess960FEN(tt.n); err != nil || got != tt.want {
+			t.Errorf("chess960FEN(%d) = %q, %v, want %q", tt.n, got, err, tt.want)
+		}
+	}
+}
+
+func TestCastleSide(t *testing.T) {
+	tests := []struct {
+		notation string
+		side     int
+		ok       bool
+	}{
+		{"O-O", kingSide, true},
+		{"0-0", kingSide, true},
+		{"O-O+", kingSide, true},
+		{"O-O-O", queenSide, true},
+		{"0-0-0#", queenSide, true},
+		{"Kg1", 0, false},
+		{"e1h1", 0, false},
+	}
+	for _, tt := range tests {
+		side, ok := castleSide(tt.notation)
+		if side != tt.side || ok != tt.ok {
+			t.Errorf("castleSide(%q) = %d, %v, want %d, %v", tt.notation, side, ok, tt.side, tt.ok)
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	tests := []struct {
+		name     string
+		position int
+		moves    []string
+		wantRank string // White's back rank once castled
+		wantUCI  string
+	}{
+		// The standard setup castles like standard chess
+		{"king side from e1", 518, []string{"e4", "e5", "Nf3", "Nf6", "Bc4", "Bc5"}, "RNBQ1RK1", "e1h1"},
+		// The king already stands on its castled square, only the rook moves
+		{"king stays on g1", 404, []string{"Ng3", "Ng6"}, "RBBQNRK1", "g1h1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := tt.position
+			g := newTestGame(t, TimeControl{InitialTime: 300}, GameSetup{Variant: VariantChess960, Chess960Position: &position})
+			play(t, g, time.Second, tt.moves...)
+			play(t, g, time.Second, "O-O")
+
+			fields := strings.Fields(g.GetFEN())
+			ranks := strings.Split(fields[0], "/")
+			if ranks[7] != tt.wantRank {
+				t.Errorf("back rank = %q, want %q", ranks[7], tt.wantRank)
+			}
+			// White has castled away both rights, Black keeps both
+			if fields[2] != "ha" {
+				t.Errorf("castling rights = %q, want %q", fields[2], "ha")
+			}
+			if got := g.History[len(g.History)-1].UCI; got != tt.wantUCI {
+				t.Errorf("UCI = %q, want %q", got, tt.wantUCI)
+			}
+		})
+	}
+}
+
+func TestChess960CastlingRejected(t *testing.T) {
+	position := 518
+	g := newTestGame(t, TimeControl{InitialTime: 300}, GameSetup{Variant: VariantChess960, Chess960Position: &position})
+	if err := g.MakeMove("white", "O-O", MoveFormatAuto); err == nil {
+		t.Error("MakeMove(O-O) error = nil with the path blocked")
+	}
+
+	// Once the king has moved, White may no longer castle
+	play(t, g, time.Second, "e4", "e5", "Nf3", "Nf6", "Bc4", "Bc5", "Ke2", "d6", "Ke1", "d5")
+	if err := g.MakeMove("white", "O-O", MoveFormatAuto); err == nil {
+		t.Error("MakeMove(O-O) error = nil after the king moved")
+	}
+}
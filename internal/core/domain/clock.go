@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxDaysPerMove bounds the move deadline of correspondence games.
+const MaxDaysPerMove = 14
+
+// ClockMode selects how time is accounted for after each move.
+type ClockMode string
+
+const (
+	ClockFischer     ClockMode = "FISCHER"      // Increment is added after every move
+	ClockBronstein   ClockMode = "BRONSTEIN"    // Time used is given back after the move, up to the delay
+	ClockSimpleDelay ClockMode = "SIMPLE_DELAY" // US delay: the clock only starts counting down after the delay
+)
+
+// ClockRule is the time accounting of one clock mode.
+type ClockRule interface {
+	// Allowance is how long a player with remaining time on the clock may
+	// think before their flag falls.
+	Allowance(remaining time.Duration) time.Duration
+	// Settle returns the time left on the clock after a move that took thinkTime.
+	Settle(remaining, thinkTime time.Duration) time.Duration
+}
+
+// correspondenceClock gives every move a fresh, fixed deadline.
+type correspondenceClock struct {
+	perMove time.Duration
+}
+
+func (c correspondenceClock) Allowance(remaining time.Duration) time.Duration {
+	return remaining
+}
+
+func (c correspondenceClock) Settle(remaining, thinkTime time.Duration) time.Duration {
+	return c.perMove
+}
+
+type fischerClock struct {
+	increment time.Duration
+}
+
+func (c fischerClock) Allowance(remaining time.Duration) time.Duration {
+	return remaining
+}
+
+func (c fischerClock) Settle(remaining, thinkTime time.Duration) time.Duration {
+	return remaining - thinkTime + c.increment
+}
+
+type bronsteinClock struct {
+	delay time.Duration
+}
+
+func (c bronsteinClock) Allowance(remaining time.Duration) time.Duration {
+	return remaining
+}
+
+func (c bronsteinClock) Settle(remaining, thinkTime time.Duration) time.Duration {
+	return remaining - thinkTime + min(thinkTime, c.delay)
+}
+
+type simpleDelayClock struct {
+	delay time.Duration
+}
+
+func (c simpleDelayClock) Allowance(remaining time.Duration) time.Duration {
+	return remaining + c.delay
+}
+
+func (c simpleDelayClock) Settle(remaining, thinkTime time.Duration) time.Duration {
+	return remaining - max(0, thinkTime-c.delay)
+}
+
+// Rule returns the clock accounting selected by the time control for a
+// player in the given stage. In staged time controls the stage's own
+// increment is the per-move bonus, whatever the clock mode.
+func (tc TimeControl) Rule(stage int) ClockRule {
+	increment, delay := time.Duration(tc.Increment)*time.Second, time.Duration(tc.Delay)*time.Second
+	if stage < len(tc.Stages) {
+		increment = time.Duration(tc.Stages[stage].Increment) * time.Second
+		delay = increment
+	}
+
+	if tc.IsCorrespondence() {
+		return correspondenceClock{perMove: tc.moveDeadline()}
+	}
+
+	switch tc.Mode {
+	case ClockBronstein:
+		return bronsteinClock{delay: delay}
+	case ClockSimpleDelay:
+		return simpleDelayClock{delay: delay}
+	}
+	return fischerClock{increment: increment}
+}
+
+// stageAfter returns the stage a player is in once they have made moves moves.
+func (tc TimeControl) stageAfter(moves int) int {
+	stage, boundary := 0, 0
+	for stage < len(tc.Stages)-1 {
+		boundary += tc.Stages[stage].Moves
+		if moves < boundary {
+			break
+		}
+		stage++
+	}
+	return stage
+}
+
+// stageTime returns the time added when a player enters stage.
+func (tc TimeControl) stageTime(stage int) time.Duration {
+	if stage >= len(tc.Stages) {
+		return 0
+	}
+	return time.Duration(tc.Stages[stage].Time) * time.Second
+}
+
+// PGNTag renders the time control in the PGN TimeControl tag format, e.g.
+// "40/5400+30:1800+30" for 40 moves in 90 minutes then 30 minutes, +30s a move.
+// The format has no notion of delay, so a delay is written in place of the
+// increment, as the most a move can give back (see IsDelay).
+func (tc TimeControl) PGNTag() string {
+	if tc.IsCorrespondence() {
+		// One move per period
+		return fmt.Sprintf("1/%d", int(tc.moveDeadline().Seconds()))
+	}
+	if len(tc.Stages) == 0 {
+		bonus := tc.Increment
+		if tc.IsDelay() {
+			bonus = tc.Delay
+		}
+		return fmt.Sprintf("%d+%d", tc.InitialTime, bonus)
+	}
+	periods := make([]string, len(tc.Stages))
+	for i, st := range tc.Stages {
+		period := fmt.Sprintf("%d", st.Time)
+		if st.Moves > 0 {
+			period = fmt.Sprintf("%d/%d", st.Moves, st.Time)
+		}
+		if st.Increment > 0 {
+			period += fmt.Sprintf("+%d", st.Increment)
+		}
+		periods[i] = period
+	}
+	return strings.Join(periods, ":")
+}
+
+// IsDelay reports whether the per-move bonus is a delay rather than a Fischer increment.
+func (tc TimeControl) IsDelay() bool {
+	return !tc.IsCorrespondence() && (tc.Mode == ClockBronstein || tc.Mode == ClockSimpleDelay)
+}
+
+// normalize fills in the default clock mode and rejects unknown ones.
+func (tc *TimeControl) normalize() error {
+	switch tc.Mode {
+	case "":
+		tc.Mode = ClockFischer
+	case ClockFischer, ClockBronstein, ClockSimpleDelay:
+	default:
+		return fmt.Errorf("%w: unknown clock mode %q", ErrInvalidSetup, tc.Mode)
+	}
+	if tc.InitialTime < 0 || tc.Increment < 0 || tc.Delay < 0 || tc.DaysPerMove < 0 {
+		return fmt.Errorf("%w: time control values cannot be negative", ErrInvalidSetup)
+	}
+
+	if tc.IsCorrespondence() {
+		if tc.DaysPerMove > MaxDaysPerMove {
+			return fmt.Errorf("%w: correspondence games allow at most %d days per move", ErrInvalidSetup, MaxDaysPerMove)
+		}
+		if len(tc.Stages) > 0 {
+			return fmt.Errorf("%w: correspondence games cannot have stages", ErrInvalidSetup)
+		}
+		// The clock simply shows the time left for the current move
+		tc.InitialTime = int(tc.moveDeadline().Seconds())
+		tc.Increment = 0
+		return nil
+	}
+
+	for i, st := range tc.Stages {
+		if st.Time < 0 || st.Increment < 0 || st.Moves < 0 {
+			return fmt.Errorf("%w: time control values cannot be negative", ErrInvalidSetup)
+		}
+		if st.Moves == 0 && i < len(tc.Stages)-1 {
+			return fmt.Errorf("%w: only the last stage can run to the end of the game", ErrInvalidSetup)
+		}
+	}
+	if len(tc.Stages) > 0 {
+		// The first stage's time is what the clocks start with
+		if tc.Stages[0].Time <= 0 {
+			return fmt.Errorf("%w: the first stage needs some time", ErrInvalidSetup)
+		}
+		tc.InitialTime = tc.Stages[0].Time
+		tc.Increment = tc.Stages[0].Increment
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockRules(t *testing.T) {
+	const s = time.Second
+	tests := []struct {
+		name      string
+		rule      ClockRule
+		remaining time.Duration
+		think     time.Duration
+		allowance time.Duration // How long the player may think before the flag falls
+		settled   time.Duration // Clock after the move
+	}{
+		{"fischer adds the increment", fischerClock{increment: 2 * s}, 60 * s, 10 * s, 60 * s, 52 * s},
+		{"fischer instant move", fischerClock{increment: 2 * s}, 60 * s, 0, 60 * s, 62 * s},
+		{"fischer at the flag", fischerClock{increment: 2 * s}, 60 * s, 60 * s, 60 * s, 2 * s},
+		{"fischer without increment", fischerClock{}, 60 * s, 15 * s, 60 * s, 45 * s},
+
+		{"bronstein gives back the time used", bronsteinClock{delay: 5 * s}, 60 * s, 3 * s, 60 * s, 60 * s},
+		{"bronstein gives back at most the delay", bronsteinClock{delay: 5 * s}, 60 * s, 10 * s, 60 * s, 55 * s},
+		{"bronstein exactly the delay", bronsteinClock{delay: 5 * s}, 60 * s, 5 * s, 60 * s, 60 * s},
+		{"bronstein at the flag", bronsteinClock{delay: 5 * s}, 60 * s, 60 * s, 60 * s, 5 * s},
+
+		{"simple delay within the delay", simpleDelayClock{delay: 5 * s}, 60 * s, 3 * s, 65 * s, 60 * s},
+		{"simple delay exactly the delay", simpleDelayClock{delay: 5 * s}, 60 * s, 5 * s, 65 * s, 60 * s},
+		{"simple delay past the delay", simpleDelayClock{delay: 5 * s}, 60 * s, 12 * s, 65 * s, 53 * s},
+		{"simple delay at the flag", simpleDelayClock{delay: 5 * s}, 60 * s, 65 * s, 65 * s, 0},
+
+		{"correspondence resets the deadline", correspondenceClock{perMove: 72 * time.Hour}, time.Hour, 30 * time.Minute, time.Hour, 72 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Allowance(tt.remaining); got != tt.allowance {
+				t.Errorf("Allowance(%v) = %v, want %v", tt.remaining, got, tt.allowance)
+			}
+			if got := tt.rule.Settle(tt.remaining, tt.think); got != tt.settled {
+				t.Errorf("Settle(%v, %v) = %v, want %v", tt.remaining, tt.think, got, tt.settled)
+			}
+		})
+	}
+}
+
+func TestTimeControlRule(t *testing.T) {
+	staged := TimeControl{
+		Mode:   ClockBronstein,
+		Delay:  10,
+		Stages: []TimeStage{{Moves: 40, Time: 5400, Increment: 30}, {Time: 1800, Increment: 5}},
+	}
+
+	tests := []struct {
+		name  string
+		tc    TimeControl
+		stage int
+		want  ClockRule
+	}{
+		{"default is fischer", TimeControl{InitialTime: 180, Increment: 2}, 0, fischerClock{increment: 2 * time.Second}},
+		{"bronstein", TimeControl{InitialTime: 180, Mode: ClockBronstein, Delay: 3}, 0, bronsteinClock{delay: 3 * time.Second}},
+		{"simple delay", TimeControl{InitialTime: 180, Mode: ClockSimpleDelay, Delay: 5}, 0, simpleDelayClock{delay: 5 * time.Second}},
+		{"stage bonus replaces the delay", staged, 0, bronsteinClock{delay: 30 * time.Second}},
+		{"later stage", staged, 1, bronsteinClock{delay: 5 * time.Second}},
+		{"correspondence", TimeControl{DaysPerMove: 3}, 0, correspondenceClock{perMove: 72 * time.Hour}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tc.Rule(tt.stage); got != tt.want {
+				t.Errorf("Rule(%d) = %#v, want %#v", tt.stage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeControlNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		tc      TimeControl
+		wantErr bool
+	}{
+		{"fischer by default", TimeControl{InitialTime: 300, Increment: 3}, false},
+		{"unknown mode", TimeControl{InitialTime: 300, Mode: "HOURGLASS"}, true},
+		{"negative delay", TimeControl{InitialTime: 300, Mode: ClockBronstein, Delay: -1}, true},
+		{"correspondence", TimeControl{DaysPerMove: MaxDaysPerMove}, false},
+		{"correspondence too long", TimeControl{DaysPerMove: MaxDaysPerMove + 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tc.normalize()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.tc.Mode == "" {
+				t.Errorf("normalize() left the clock mode empty")
+			}
+		})
+	}
+}
+
+func TestStageAfter(t *testing.T) {
+	tc := TimeControl{Stages: []TimeStage{{Moves: 40, Time: 5400}, {Moves: 20, Time: 1800}, {Time: 900}}}
+	tests := []struct {
+		moves int
+		want  int
+	}{
+		{0, 0},
+		{39, 0},
+		{40, 1},
+		{59, 1},
+		{60, 2},
+		{200, 2},
+	}
+	for _, tt := range tests {
+		if got := tc.stageAfter(tt.moves); got != tt.want {
+			t.Errorf("stageAfter(%d) = %d, want %d", tt.moves, got, tt.want)
+		}
+	}
+
+	if got := (TimeControl{InitialTime: 300}).stageAfter(100); got != 0 {
+		t.Errorf("stageAfter() without stages = %d, want 0", got)
+	}
+}
+
+func TestStageTime(t *testing.T) {
+	tc := TimeControl{Stages: []TimeStage{{Moves: 40, Time: 5400}, {Time: 1800}}}
+	tests := []struct {
+		stage int
+		want  time.Duration
+	}{
+		{0, 90 * time.Minute},
+		{1, 30 * time.Minute},
+		{2, 0},
+	}
+	for _, tt := range tests {
+		if got := tc.stageTime(tt.stage); got != tt.want {
+			t.Errorf("stageTime(%d) = %v, want %v", tt.stage, got, tt.want)
+		}
+	}
+}
+
+func TestTimeControlPGNTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   TimeControl
+		want string
+	}{
+		{"fischer", TimeControl{InitialTime: 180, Increment: 2}, "180+2"},
+		{"delay in place of the increment", TimeControl{InitialTime: 300, Mode: ClockBronstein, Delay: 5}, "300+5"},
+		{"staged", TimeControl{Stages: []TimeStage{{Moves: 40, Time: 5400, Increment: 30}, {Time: 1800, Increment: 30}}}, "40/5400+30:1800+30"},
+		{"staged without increment", TimeControl{Stages: []TimeStage{{Moves: 40, Time: 7200}, {Moves: 20, Time: 3600}, {Time: 900}}}, "40/7200:20/3600:900"},
+		{"correspondence", TimeControl{DaysPerMove: 3}, "1/259200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tc.PGNTag(); got != tt.want {
+				t.Errorf("PGNTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeControlNormalizeStages(t *testing.T) {
+	tc := TimeControl{Stages: []TimeStage{{Moves: 40, Time: 5400, Increment: 30}, {Time: 1800}}}
+	if err := tc.normalize(); err != nil {
+		t.Fatalf("normalize() error = %v", err)
+	}
+	// The clocks start from the first stage
+	if tc.InitialTime != 5400 || tc.Increment != 30 {
+		t.Errorf("InitialTime, Increment = %d, %d, want 5400, 30", tc.InitialTime, tc.Increment)
+	}
+
+	invalid := []TimeControl{
+		{Stages: []TimeStage{{Time: 5400}, {Time: 1800}}},                         // Open-ended stage before the last
+		{Stages: []TimeStage{{Moves: 40}, {Time: 1800}}},                          // No time to start with
+		{DaysPerMove: 1, Stages: []TimeStage{{Moves: 40, Time: 5400}, {Time: 1}}}, // Correspondence with stages
+	}
+	for _, tc := range invalid {
+		if err := tc.normalize(); err == nil {
+			t.Errorf("normalize(%+v) error = nil, want an error", tc.Stages)
+		}
+	}
+}
+
+func TestStageTimeAdded(t *testing.T) {
+	tc := TimeControl{Stages: []TimeStage{{Moves: 2, Time: 60}, {Time: 30}}}
+	g := newTestGame(t, tc, GameSetup{})
+	play(t, g, time.Second, "e4", "e5", "Nf3")
+
+	// White's second move completes the first stage and brings its time
+	nf3 := g.History[2]
+	if got := nf3.ClockAfter - nf3.ClockBefore; got <= 25*time.Second || got > 30*time.Second {
+		t.Errorf("clock gained %v entering the second stage, want just under 30s", got)
+	}
+	if g.White.Stage != 1 || g.Black.Stage != 0 {
+		t.Errorf("stages = %d, %d, want 1, 0", g.White.Stage, g.Black.Stage)
+	}
+}
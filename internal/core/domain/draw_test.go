@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimDraw(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    GameSetup
+		moves    []string
+		claimant string
+		want     Termination // "" when the claim must be refused
+	}{
+		{
+			name:     "threefold repetition",
+			moves:    []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8"},
+			claimant: "white",
+			want:     TerminationRepetition,
+		},
+		{
+			name:     "position seen only twice",
+			moves:    []string{"Nf3", "Nf6", "Ng1", "Ng8"},
+			claimant: "white",
+		},
+		{
+			name:     "fifty moves",
+			setup:    GameSetup{StartFEN: "7k/8/8/8/8/8/8/KR6 w - - 99 80"},
+			moves:    []string{"Rb2"},
+			claimant: "black",
+			want:     TerminationFiftyMoveRule,
+		},
+		{
+			name:     "pawn move resets the count",
+			setup:    GameSetup{StartFEN: "7k/8/8/8/8/8/P7/KR6 w - - 99 80"},
+			moves:    []string{"a3"},
+			claimant: "black",
+		},
+		{
+			name:     "not on turn",
+			moves:    []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8"},
+			claimant: "black",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, TimeControl{InitialTime: 300}, tt.setup)
+			play(t, g, time.Second, tt.moves...)
+
+			err := g.ClaimDraw(tt.claimant)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatal("ClaimDraw() error = nil, want the claim refused")
+				}
+				if g.IsGameOver() {
+					t.Error("a refused claim ended the game")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ClaimDraw() error = %v", err)
+			}
+			if g.Outcome != OutcomeDraw || g.Termination != tt.want {
+				t.Errorf("game ended %s by %s, want %s by %s", g.Outcome, g.Termination, OutcomeDraw, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimableDrawsTracksPosition(t *testing.T) {
+	g := newTestGame(t, TimeControl{InitialTime: 300}, GameSetup{})
+	play(t, g, time.Second, "Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8")
+	if len(g.ClaimableDraws) != 1 || g.ClaimableDraws[0] != TerminationRepetition {
+		t.Fatalf("ClaimableDraws = %v, want [%s]", g.ClaimableDraws, TerminationRepetition)
+	}
+
+	// A new position leaves nothing to claim
+	play(t, g, time.Second, "e4")
+	if len(g.ClaimableDraws) != 0 {
+		t.Errorf("ClaimableDraws = %v after a new position, want none", g.ClaimableDraws)
+	}
+}
+
+func TestSeenByShowsOffersToTheirAddresseeOnly(t *testing.T) {
+	g := &Game{
+		White:           Participant{UserID: "white"},
+		Black:           Participant{UserID: "black"},
+		DrawOffer:       &Offer{PlayerID: "white"},
+		TakebackRequest: &Offer{PlayerID: "black"},
+	}
+
+	tests := []struct {
+		viewer       string
+		wantDraw     bool
+		wantTakeback bool
+	}{
+		{"white", false, true},
+		{"black", true, false},
+		{"spectator", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		view := g.SeenBy(tt.viewer)
+		if (view.DrawOffer != nil) != tt.wantDraw || (view.TakebackRequest != nil) != tt.wantTakeback {
+			t.Errorf("SeenBy(%q) shows draw offer %v, takeback request %v, want %v, %v",
+				tt.viewer, view.DrawOffer != nil, view.TakebackRequest != nil, tt.wantDraw, tt.wantTakeback)
+		}
+	}
+	if g.DrawOffer == nil || g.TakebackRequest == nil {
+		t.Error("SeenBy() changed the game itself")
+	}
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/notnil/chess"
+)
+
+// maxCachedEngines bounds the cache; live games beyond it simply replay their history.
+const maxCachedEngines = 10000
+
+// EngineCache keeps the engine of recently saved games in memory, so loading
+// a game does not have to replay its whole history on every move.
+type EngineCache struct {
+	mu      sync.Mutex
+	engines map[uuid.UUID]cachedEngine
+}
+
+type cachedEngine struct {
+	engine  *chess.Game
+	version int64 // Game.Version the engine was stored at
+}
+
+func NewEngineCache() *EngineCache {
+	return &EngineCache{
+		engines: make(map[uuid.UUID]cachedEngine),
+	}
+}
+
+// Store remembers the engine of game as of its current version. It keeps a
+// copy, as the caller goes on using the game it just saved.
+func (c *EngineCache) Store(g *Game) {
+	if g.internalGame == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.engines[g.ID]; !exists && len(c.engines) >= maxCachedEngines {
+		// Evict an arbitrary entry, it will just be replayed next time
+		for id := range c.engines {
+			delete(c.engines, id)
+			break
+		}
+	}
+	c.engines[g.ID] = cachedEngine{
+		engine:  g.internalGame.Clone(),
+		version: g.Version,
+	}
+}
+
+// Forget drops the cached engine of a game, e.g. once it leaves live storage.
+func (c *EngineCache) Forget(id uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.engines, id)
+}
+
+// take returns a copy of the cached engine for g if it was stored at the
+// version g was loaded at. Matching the version rather than the position
+// matters: the same position reached by another move order has another
+// history, and so other repetition counts.
+func (c *EngineCache) take(g *Game) *chess.Game {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.engines[g.ID]
+	if !ok || cached.version != g.Version {
+		return nil
+	}
+	// Every loaded copy of the game gets an engine of its own
+	return cached.engine.Clone()
+}
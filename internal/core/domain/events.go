@@ -12,7 +12,11 @@ type GameEventType string
 const (
 	EventGameStarted  GameEventType = "GAME_STARTED"
 	EventMoveMade     GameEventType = "MOVE_MADE"
+	EventGameUpdated  GameEventType = "GAME_UPDATED" // Any change to a game that did not end it
 	EventGameFinished GameEventType = "GAME_FINISHED"
+	EventTimeGiven    GameEventType = "TIME_GIVEN" // Payload is a TimeGift
+	// A player stayed away past the grace period; their opponent may claim the game
+	EventAbandonmentClaimable GameEventType = "ABANDONMENT_CLAIMABLE"
 )
 
 // GameEvent is a generic structure to represent changes in the domain
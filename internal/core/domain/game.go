@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,119 +18,188 @@ type Game struct {
 	CreatedAt  time.Time   `json:"created_at"`
 	UpdatedAt  time.Time   `json:"updated_at"`
 	CurrentFEN string      `json:"current_fen"` // Add this!
+	Version    int64       `json:"version"`     // Bumped on every update, to detect concurrent writes
 
-	WinnerID     string `json:"winner_id,omitempty"` // "" for draw, or the player's UserID
-	ResultReason string `json:"result_reason"`       // "CHECKMATE", "TIMEOUT", "STALEMATE", etc.
-	IsFinished   bool   `json:"is_finished"`
+	Variant          Variant `json:"variant"`
+	StartFEN         string  `json:"start_fen"`                   // Position the game started from (Shredder-FEN castling for Chess960)
+	Chess960Position *int    `json:"chess960_position,omitempty"` // 0-959, only for Chess960
+	Odds             *Odds   `json:"odds,omitempty"`              // Material handicap, only for standard games
+	Rated            bool    `json:"rated"`                       // Counts towards the players' ratings
 
+	Ratings *GameRatings `json:"ratings,omitempty"` // Rating changes, once a rated game is finished
+
+	State       GameState   `json:"state"`
+	Outcome     Outcome     `json:"outcome,omitempty"`     // Set once State is FINISHED
+	Termination Termination `json:"termination,omitempty"` // Set once State is FINISHED
+	WinnerID    string      `json:"winner_id,omitempty"`   // "" for draw, or the player's UserID
+
+	DrawOffer       *Offer `json:"draw_offer,omitempty"`       // Pending draw offer, if any
+	TakebackRequest *Offer `json:"takeback_request,omitempty"` // Pending takeback request, if any
+	PauseRequest    *Offer `json:"pause_request,omitempty"`    // Pending request to adjourn, if any
+	ResumeRequest   *Offer `json:"resume_request,omitempty"`   // Pending request to resume a paused game, if any
+
+	PausedAt *time.Time `json:"paused_at,omitempty"` // When the clocks were frozen, while PAUSED
+
+	// Events logs what happened off the board, e.g. time given to the opponent
+	Events []GameEvent `json:"events,omitempty"`
+
+	// Draws the side to move may claim (REPETITION, FIFTY_MOVE_RULE). Fivefold
+	// repetition and the 75-move rule need no claim, the game ends on its own.
+	ClaimableDraws []Termination `json:"claimable_draws,omitempty"`
+
+	// premove is kept out of JSON so the opponent never gets to see it
+	premove *Premove
 	// internalGame is not exported to JSON.
 	// We use it for move validation and state calculation (using the chess package )
 	internalGame *chess.Game
+	// castling tracks Chess960 castling rights, which the engine cannot (nil for standard games)
+	castling *castling960
 }
 
 // NewGame is a Factory function to initialize a game correctly
-func NewGame(whiteID, blackID string, tc TimeControl) *Game {
+func NewGame(whiteID, blackID string, tc TimeControl, setup GameSetup) (*Game, error) {
 	game := &Game{
-		ID:           uuid.New(),
-		White:        Participant{UserID: whiteID, Status: StatusOnline, TimeRemaining: time.Duration(tc.InitialTime) * time.Second},
-		Black:        Participant{UserID: blackID, Status: StatusOnline, TimeRemaining: time.Duration(tc.InitialTime) * time.Second},
-		Settings:     tc,
-		History:      []Move{},
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-		internalGame: chess.NewGame(),
+		ID:        uuid.New(),
+		White:     Participant{UserID: whiteID, Status: StatusOnline},
+		Black:     Participant{UserID: blackID, Status: StatusOnline},
+		Settings:  tc,
+		History:   []Move{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		State:     StateCreated,
+	}
+	if err := game.Settings.normalize(); err != nil {
+		return nil, err
+	}
+	// Clocks start from the normalized time control, which may have been
+	// derived from stages or days per move; setup can still override a side
+	game.White.TimeRemaining = time.Duration(game.Settings.InitialTime) * time.Second
+	game.Black.TimeRemaining = time.Duration(game.Settings.InitialTime) * time.Second
+	if err := setup.apply(game); err != nil {
+		return nil, err
+	}
+	// Sets up the engine at the starting position
+	if err := game.replayHistory(); err != nil {
+		return nil, err
 	}
+	game.White.LastDrawOfferMove = -1
+	game.Black.LastDrawOfferMove = -1
 	// Initialize the JSON-friendly fields
 	game.White.SyncTime()
 	game.Black.SyncTime()
-	return game
+	return game, nil
 }
 
-func (g *Game) MakeMove(playerID string, moveNotation string) error {
-	if g.IsFinished || g.IsGameOver() {
-		return errors.New("game is already finished")
+// MakeMove plays a move given in format (MoveFormatAuto to detect it).
+func (g *Game) MakeMove(playerID string, moveNotation string, format MoveFormat) error {
+	now := time.Now()
+	if err := g.playMove(playerID, moveNotation, format, now, false); err != nil {
+		return err
+	}
+	// The opponent may have queued their reply already
+	g.playPremove(now)
+	return nil
+}
+
+// playMove applies one move at now. A premove is played the instant it
+// becomes legal, so it is charged no think time.
+func (g *Game) playMove(playerID string, moveNotation string, format MoveFormat, now time.Time, premove bool) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	format, err := format.resolve(moveNotation)
+	if err != nil {
+		return err
 	}
 
-	now := time.Now()
 	currentTurn := g.internalGame.Position().Turn()
+	mover, opponent := g.sideToMove()
+	clockBefore := mover.TimeRemaining
+	clockAfter := clockBefore
+	var thinkTime time.Duration // The clock only runs from the second move on
 
 	// 1. CLOCK LOGIC & TIMEOUT PROTECTION
 	if len(g.History) > 0 {
-		thinkTime := now.Sub(g.UpdatedAt)
+		if playerID != mover.UserID {
+			return errors.New("it is not your turn")
+		}
 
-		if currentTurn == chess.White {
-			if playerID != g.White.UserID {
-				return errors.New("it is not your turn")
-			}
-			g.White.TimeRemaining -= thinkTime
-			g.White.TimeRemaining += time.Duration(g.Settings.Increment) * time.Second
-
-			// Check White's timeout specifically
-			if g.White.TimeRemaining <= 0 {
-				g.White.TimeRemaining = 0
-				g.White.SyncTime()
-				g.Black.SyncTime() // Sync Black so they keep their remaining time
-				g.finishGame(g.Black.UserID, "TIMEOUT")
-				return nil
-			}
-		} else {
-			if playerID != g.Black.UserID {
-				return errors.New("it is not your turn")
-			}
-			g.Black.TimeRemaining -= thinkTime
-			g.Black.TimeRemaining += time.Duration(g.Settings.Increment) * time.Second
-
-			// Check Black's timeout specifically
-			if g.Black.TimeRemaining <= 0 {
-				g.Black.TimeRemaining = 0
-				g.Black.SyncTime()
-				g.White.SyncTime() // Sync White so they keep their remaining time
-				g.finishGame(g.White.UserID, "TIMEOUT")
-				return nil
-			}
+		thinkTime = now.Sub(g.UpdatedAt)
+		if premove {
+			thinkTime = 0
 		}
+		rule := g.clockOf(mover).Rule(mover.Stage)
+
+		// Check the mover's timeout before any time is credited back
+		if thinkTime >= rule.Allowance(mover.TimeRemaining) {
+			return g.flagFell(mover, opponent)
+		}
+		clockAfter = rule.Settle(mover.TimeRemaining, thinkTime)
 	} else {
-		// First move: Just verify the player is White
-		if playerID != g.White.UserID {
+		// First move: Just verify the player is the side to move of the starting position
+		if playerID != mover.UserID {
+			if currentTurn == chess.Black {
+				return errors.New("black must start the game")
+			}
 			return errors.New("white must start the game")
 		}
 	}
 
 	// 2. APPLY TO ENGINE
-	err := g.internalGame.MoveStr(moveNotation)
+	fenBefore := g.GetFEN()
+	move, err := g.applyMove(moveNotation, format)
 	if err != nil {
 		return errors.New("invalid move format")
 	}
 
+	// Only a legal move gets charged to the clock
+	mover.TimeRemaining = clockAfter
+
+	// Completing a stage's moves opens the next stage and its extra time
+	if next := g.clockOf(mover).stageAfter(g.movesBy(playerID) + 1); next > mover.Stage {
+		for stage := mover.Stage + 1; stage <= next; stage++ {
+			mover.TimeRemaining += g.clockOf(mover).stageTime(stage)
+		}
+		mover.Stage = next
+	}
+
+	// The first move on the board starts the game proper
+	if g.State == StateWaitingForPlayers {
+		if err := g.transition(StateInProgress); err != nil {
+			return err
+		}
+	}
+
 	// Update FEN and History
-	g.CurrentFEN = g.internalGame.FEN()
-	g.History = append(g.History, Move{
-		FENBefore: g.GetFEN(),
-		Notation:  moveNotation,
-		PlayerID:  playerID,
-		Timestamp: now,
-	})
+	g.CurrentFEN = g.GetFEN()
+	move.Ply = len(g.History) + 1
+	move.FENBefore = fenBefore
+	move.FENAfter = g.CurrentFEN
+	move.PlayerID = playerID
+	move.Timestamp = now
+	move.ClockBefore = clockBefore
+	move.ClockAfter = mover.TimeRemaining
+	move.ThinkTime = thinkTime
+	g.History = append(g.History, move)
+
+	// Moving withdraws any draw offer the mover still has standing
+	if g.DrawOffer != nil && g.DrawOffer.PlayerID == playerID {
+		g.DrawOffer = nil
+	}
+	// A move on the board answers any takeback request with a "no"
+	g.TakebackRequest = nil
 
 	// Sync durations for JSON (if move was successful and no timeout)
 	g.White.SyncTime()
 	g.Black.SyncTime()
 
 	// 3. CHECK FOR ENGINE GAME OVER (Checkmate/Draw)
-	if g.IsGameOver() {
-		outcome := g.internalGame.Outcome()
-		reason := g.internalGame.Method().String()
-		var winner string
-		if outcome == chess.WhiteWon {
-			winner = g.White.UserID
-		} else if outcome == chess.BlackWon {
-			winner = g.Black.UserID
-		} else {
-			winner = "DRAW"
-		}
-		g.finishGame(winner, reason)
-	}
-
+	// The engine itself ends the game at fivefold repetition and the 75-move rule
 	g.UpdatedAt = now
+	g.ClaimableDraws = g.claimableDraws()
+	if g.internalGame.Outcome() != chess.NoOutcome {
+		return g.finishGame(engineResult(g.internalGame.Outcome(), g.internalGame.Method()))
+	}
 	return nil
 }
 
@@ -220,34 +290,606 @@ func (g *Game) MakeMove(playerID string, moveNotation string) error {
 //		g.UpdatedAt = now
 //		return nil
 //	}
-func (g *Game) finishGame(winnerID string, reason string) {
-	g.IsFinished = true
-	g.WinnerID = winnerID
-	g.ResultReason = reason
+
+// Resign ends the game in favour of the opponent of playerID. A paused game
+// can be resigned too.
+func (g *Game) Resign(playerID string) error {
+	if err := g.checkOngoing(); err != nil {
+		return err
+	}
+	opponentID, err := g.OpponentOf(playerID)
+	if err != nil {
+		return err
+	}
+
+	g.UpdatedAt = time.Now()
+	return g.finishGame(g.winFor(opponentID), TerminationResignation)
+}
+
+// OfferDraw records a draw offer from playerID. If the opponent already has an
+// offer standing, the two offers meet and the game is drawn by agreement.
+func (g *Game) OfferDraw(playerID string) error {
+	if err := g.checkOngoing(); err != nil {
+		return err
+	}
+	p, err := g.participant(playerID)
+	if err != nil {
+		return err
+	}
+
+	if g.DrawOffer != nil {
+		if g.DrawOffer.PlayerID == playerID {
+			return errors.New("draw offer already pending")
+		}
+		return g.AcceptDraw(playerID)
+	}
+
+	// One offer per move: the player has to move themselves before offering
+	// again, the opponent moving does not count
+	moves := g.movesBy(playerID)
+	if p.LastDrawOfferMove == moves {
+		return errors.New("you can only offer a draw once per move")
+	}
+
+	p.LastDrawOfferMove = moves
+	g.DrawOffer = &Offer{PlayerID: playerID, Ply: len(g.History), CreatedAt: time.Now()}
+	return nil
+}
+
+// AcceptDraw ends the game as a draw if the opponent of playerID has an offer pending.
+func (g *Game) AcceptDraw(playerID string) error {
+	if err := g.checkDrawOfferFor(playerID); err != nil {
+		return err
+	}
+
+	g.UpdatedAt = time.Now()
+	return g.finishGame(OutcomeDraw, TerminationAgreement)
+}
+
+// DeclineDraw rejects the opponent's pending draw offer.
+func (g *Game) DeclineDraw(playerID string) error {
+	if err := g.checkDrawOfferFor(playerID); err != nil {
+		return err
+	}
+
+	g.DrawOffer = nil
+	return nil
+}
+
+// checkDrawOfferFor verifies there is a draw offer that playerID is allowed to answer.
+func (g *Game) checkDrawOfferFor(playerID string) error {
+	if err := g.checkOngoing(); err != nil {
+		return err
+	}
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	if g.DrawOffer == nil || g.DrawOffer.PlayerID == playerID {
+		return errors.New("no draw offer to answer")
+	}
+	return nil
+}
+
+// ClaimDraw ends the game as a draw on a claim by the side to move, when the
+// position allows one: threefold repetition or fifty moves without a capture
+// or pawn move.
+func (g *Game) ClaimDraw(playerID string) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	if !g.IsTurnOf(playerID) {
+		return errors.New("a draw can only be claimed on your turn")
+	}
+	claims := g.claimableDraws()
+	if len(claims) == 0 {
+		return errors.New("no draw can be claimed in this position")
+	}
+
+	g.UpdatedAt = time.Now()
+	return g.finishGame(OutcomeDraw, claims[0])
+}
+
+// claimableDraws returns the draws the side to move could claim right now.
+func (g *Game) claimableDraws() []Termination {
+	if g.checkPlayable() != nil {
+		return nil
+	}
+	var claims []Termination
+	for _, method := range g.internalGame.EligibleDraws() {
+		switch method {
+		case chess.ThreefoldRepetition:
+			claims = append(claims, TerminationRepetition)
+		case chess.FiftyMoveRule:
+			claims = append(claims, TerminationFiftyMoveRule)
+		}
+	}
+	return claims
+}
+
+// RequestTakeback asks the opponent to undo playerID's last move. If the
+// opponent has replied since, their reply is taken back as well.
+func (g *Game) RequestTakeback(playerID string) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if g.Settings.DisallowTakebacks {
+		return errors.New("takebacks are not allowed in this game")
+	}
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	if g.TakebackRequest != nil {
+		if g.TakebackRequest.PlayerID == playerID {
+			return errors.New("takeback request already pending")
+		}
+		return errors.New("answer your opponent's takeback request first")
+	}
+	if g.takebackPlies(playerID) == 0 {
+		return errors.New("no move to take back")
+	}
+
+	g.TakebackRequest = &Offer{PlayerID: playerID, Ply: len(g.History), CreatedAt: time.Now()}
+	return nil
+}
+
+// AcceptTakeback undoes the requester's last move (and any reply to it),
+// restoring the position and the clocks to how they were before that move.
+func (g *Game) AcceptTakeback(playerID string) error {
+	if err := g.checkTakebackFor(playerID); err != nil {
+		return err
+	}
+
+	plies := g.takebackPlies(g.TakebackRequest.PlayerID)
+	if plies == 0 {
+		return errors.New("no move to take back")
+	}
+
+	// Walk backwards so the oldest popped move decides the final clock value
+	kept := len(g.History) - plies
+	for i := len(g.History) - 1; i >= kept; i-- {
+		m := g.History[i]
+		if m.PlayerID == g.White.UserID {
+			g.White.TimeRemaining = m.ClockBefore
+		} else {
+			g.Black.TimeRemaining = m.ClockBefore
+		}
+	}
+	g.History = g.History[:kept]
+	g.White.Stage = g.clockOf(&g.White).stageAfter(g.movesBy(g.White.UserID))
+	g.Black.Stage = g.clockOf(&g.Black).stageAfter(g.movesBy(g.Black.UserID))
+
+	if err := g.replayHistory(); err != nil {
+		return err
+	}
+
+	g.TakebackRequest = nil
+	g.DrawOffer = nil
+	g.premove = nil
+	g.White.SyncTime()
+	g.Black.SyncTime()
+	// Restart the clock of the side that is now to move
+	g.UpdatedAt = time.Now()
+	return nil
+}
+
+// DeclineTakeback rejects the opponent's pending takeback request.
+func (g *Game) DeclineTakeback(playerID string) error {
+	if err := g.checkTakebackFor(playerID); err != nil {
+		return err
+	}
+
+	g.TakebackRequest = nil
+	return nil
+}
+
+// checkTakebackFor verifies there is a takeback request that playerID is allowed to answer.
+func (g *Game) checkTakebackFor(playerID string) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	if g.TakebackRequest == nil || g.TakebackRequest.PlayerID == playerID {
+		return errors.New("no takeback request to answer")
+	}
+	return nil
+}
+
+// takebackPlies returns how many entries must be popped from History to undo
+// playerID's last move: 1 if it was the last move played, 2 if the opponent
+// has already replied, 0 if the player has no move to take back.
+func (g *Game) takebackPlies(playerID string) int {
+	n := len(g.History)
+	if n > 0 && g.History[n-1].PlayerID == playerID {
+		return 1
+	}
+	if n > 1 && g.History[n-2].PlayerID == playerID {
+		return 2
+	}
+	return 0
+}
+
+// SetPremove queues a move for playerID to be played the instant their
+// opponent has moved. A new premove replaces the one already queued.
+func (g *Game) SetPremove(playerID string, moveNotation string, format MoveFormat) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	if g.IsTurnOf(playerID) {
+		return errors.New("it is your turn, make the move instead")
+	}
+	if strings.TrimSpace(moveNotation) == "" {
+		return errors.New("premove cannot be empty")
+	}
+	if _, err := format.resolve(moveNotation); err != nil {
+		return err
+	}
+
+	g.premove = &Premove{PlayerID: playerID, Notation: moveNotation, Format: format}
+	return nil
+}
+
+// CancelPremove drops the move playerID has queued.
+func (g *Game) CancelPremove(playerID string) error {
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	if g.premove == nil || g.premove.PlayerID != playerID {
+		return errors.New("no premove to cancel")
+	}
+
+	g.premove = nil
+	return nil
+}
+
+// PendingPremove returns the queued premove, if any. It is only meant for
+// repositories, which have to persist it alongside the game.
+func (g *Game) PendingPremove() *Premove {
+	return g.premove
+}
+
+// RestorePremove puts back a premove a repository loaded with the game.
+func (g *Game) RestorePremove(p *Premove) {
+	g.premove = p
+}
+
+// playPremove plays the move queued by the side now to move, if there is
+// one. A premove the position no longer allows is dropped without complaint.
+func (g *Game) playPremove(now time.Time) {
+	p := g.premove
+	if p == nil || !g.IsTurnOf(p.PlayerID) {
+		return
+	}
+	g.premove = nil
+	_ = g.playMove(p.PlayerID, p.Notation, p.Format, now, true)
+}
+
+// movesBy counts the moves playerID has made so far.
+func (g *Game) movesBy(playerID string) int {
+	n := 0
+	for _, m := range g.History {
+		if m.PlayerID == playerID {
+			n++
+		}
+	}
+	return n
+}
+
+// replayHistory rebuilds the engine by playing every move in History from the start position.
+func (g *Game) replayHistory() error {
+	engine, err := g.startEngine()
+	if err != nil {
+		return err
+	}
+	g.internalGame = engine
+	if err := g.loadCastling(g.StartFEN); err != nil {
+		return err
+	}
+
+	for _, m := range g.History {
+		if _, err := g.applyMove(m.Notation, MoveFormatSAN); err != nil {
+			return err
+		}
+	}
+	g.CurrentFEN = g.GetFEN()
+	g.ClaimableDraws = g.claimableDraws()
+	return nil
+}
+
+// startEngine returns an engine set up at the game's starting position.
+func (g *Game) startEngine() (*chess.Game, error) {
+	if g.StartFEN == "" {
+		return chess.NewGame(), nil
+	}
+	fen := g.StartFEN
+	if g.Variant == VariantChess960 {
+		// Castling is played by castle960, the engine must never castle itself
+		fen = withCastlingField(fen, "-")
+	}
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	return chess.NewGame(opt), nil
+}
+
+// loadCastling reads the Chess960 castling rights from fen, against the
+// position the engine is currently in.
+func (g *Game) loadCastling(fen string) error {
+	g.castling = nil
+	if g.Variant != VariantChess960 {
+		return nil
+	}
+	fields := strings.Fields(fen)
+	if len(fields) < 3 {
+		return errors.New("invalid FEN")
+	}
+	castling, err := parseCastling960(fields[2], g.internalGame.Position().Board().SquareMap())
+	if err != nil {
+		return err
+	}
+	g.castling = castling
+	return nil
+}
+
+// applyMove plays a move on the engine, taking care of Chess960 castling
+// itself. It returns the board side of the move record: canonical SAN, UCI
+// and what the move did.
+func (g *Game) applyMove(notation string, format MoveFormat) (Move, error) {
+	if g.castling != nil {
+		if side, ok := g.castleSideOf(notation, format); ok {
+			return g.playCastle960(side)
+		}
+	}
+
+	pos := g.internalGame.Position()
+	move, err := decodeMove(pos, notation, format)
+	if err != nil {
+		return Move{}, err
+	}
+	record := Move{
+		Notation:  chess.AlgebraicNotation{}.Encode(pos, move),
+		UCI:       chess.UCINotation{}.Encode(pos, move),
+		EnPassant: move.HasTag(chess.EnPassant),
+		Promotion: move.Promo().String(),
+	}
+	if captured := pos.Board().Piece(move.S2()); captured != chess.NoPiece {
+		record.Captured = captured.Type().String()
+	} else if record.EnPassant {
+		record.Captured = chess.Pawn.String()
+	}
+
+	if err := g.internalGame.Move(move); err != nil {
+		return Move{}, err
+	}
+	if g.castling != nil {
+		g.castling.update(g.internalGame.Position().Board().SquareMap())
+	}
+	record.markSAN()
+	return record, nil
+}
+
+// playCastle960 castles on side and returns the move record. In UCI, Chess960
+// castling is written as the king capturing its own rook.
+func (g *Game) playCastle960(side int) (Move, error) {
+	color := g.internalGame.Position().Turn()
+	ci, rank := colorIndex(color), backRankOf(color)
+	if err := g.castle960(side); err != nil {
+		return Move{}, err
+	}
+	record := Move{
+		// The rights are only updated below, so they still name the squares castled from
+		UCI: square(g.castling.kingFile[ci], rank).String() + square(g.castling.rooks[ci][side], rank).String(),
+	}
+	// Castling uses up both rights, even when the king ends on the square it
+	// started from and so would keep them by update's reckoning
+	g.castling.rooks[ci] = [2]int{-1, -1}
+
+	record.Notation = "O-O"
+	if side == queenSide {
+		record.Notation = "O-O-O"
+	}
+	record.Notation += checkSuffix(g.internalGame.Position())
+	record.markSAN()
+	return record, nil
+}
+
+// NextDeadline returns the next moment the game can end without anyone
+// acting: the first-move deadline while the opening moves are pending, or
+// the side to move running out of time. The second value is false when no
+// such deadline exists (e.g. the game is over).
+func (g *Game) NextDeadline() (time.Time, bool) {
+	if g.checkPlayable() != nil {
+		return time.Time{}, false
+	}
+
+	var deadline time.Time
+	if len(g.History) < 2 {
+		deadline = g.firstMoveDeadline()
+	}
+	// The clock only runs once the first move has been played
+	if len(g.History) > 0 {
+		mover, _ := g.sideToMove()
+		flag := g.UpdatedAt.Add(g.clockOf(mover).Rule(mover.Stage).Allowance(mover.TimeRemaining))
+		if deadline.IsZero() || flag.Before(deadline) {
+			deadline = flag
+		}
+	}
+	return deadline, true
+}
+
+// CheckDeadline ends the game if a deadline has passed by now, without
+// waiting for anyone to attempt a move: a missed first move aborts the game,
+// a side to move out of time loses on TIMEOUT. It reports whether the game
+// was ended.
+func (g *Game) CheckDeadline(now time.Time) bool {
+	if g.checkPlayable() != nil {
+		return false
+	}
+
+	if len(g.History) < 2 && !now.Before(g.firstMoveDeadline()) {
+		g.UpdatedAt = now
+		return g.abortGame() == nil
+	}
+
+	if len(g.History) == 0 {
+		return false
+	}
+	mover, opponent := g.sideToMove()
+	if now.Before(g.UpdatedAt.Add(g.clockOf(mover).Rule(mover.Stage).Allowance(mover.TimeRemaining))) {
+		return false
+	}
+
+	g.UpdatedAt = now
+	return g.flagFell(mover, opponent) == nil
+}
+
+// flagFell ends the game for the side to move having run out of time: a loss,
+// or a draw when the opponent has no material left to checkmate with.
+func (g *Game) flagFell(mover, opponent *Participant) error {
+	mover.TimeRemaining = 0
+	g.White.SyncTime()
+	g.Black.SyncTime() // Sync both so the opponent keeps their remaining time
+
+	board := g.internalGame.Position().Board().SquareMap()
+	if !canCheckmate(board, g.internalGame.Position().Turn().Other()) {
+		return g.finishGame(OutcomeDraw, TerminationTimeoutVsInsufficientMaterial)
+	}
+	return g.finishGame(g.winFor(opponent.UserID), TerminationTimeout)
+}
+
+// Abort cancels the game without a result. It is only possible until both
+// players have made their first move.
+func (g *Game) Abort(playerID string) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	if len(g.History) >= 2 {
+		return errors.New("game can only be aborted before both players have moved")
+	}
+
+	g.UpdatedAt = time.Now()
+	return g.abortGame()
+}
+
+// firstMoveDeadline is when the player owing their first move forfeits the
+// game start: counted from creation for the first mover, and from the first
+// move for their opponent.
+func (g *Game) firstMoveDeadline() time.Time {
+	since := g.CreatedAt
+	if len(g.History) > 0 {
+		since = g.History[0].Timestamp
+	}
+	return since.Add(g.Settings.FirstMoveTimeLimit())
+}
+
+// sideToMove returns the participant whose clock is running and their opponent.
+func (g *Game) sideToMove() (mover *Participant, opponent *Participant) {
+	if g.internalGame.Position().Turn() == chess.White {
+		return &g.White, &g.Black
+	}
+	return &g.Black, &g.White
+}
+
+// IsTurnOf reports whether the game is waiting for a move from playerID.
+func (g *Game) IsTurnOf(playerID string) bool {
+	if g.checkPlayable() != nil {
+		return false
+	}
+	mover, _ := g.sideToMove()
+	return mover.UserID == playerID
+}
+
+// OpponentOf returns the UserID of the other participant, or an error if
+// playerID is not seated in this game.
+func (g *Game) OpponentOf(playerID string) (string, error) {
+	switch playerID {
+	case g.White.UserID:
+		return g.Black.UserID, nil
+	case g.Black.UserID:
+		return g.White.UserID, nil
+	}
+	return "", errors.New("player is not part of this game")
+}
+
+// SeenBy returns the game as viewerID is allowed to see it: a pending offer or
+// request is only shown to the player it is addressed to, never to the rest
+// of the room. The view shares everything else with g and must not be changed.
+func (g *Game) SeenBy(viewerID string) *Game {
+	view := *g
+	for _, offer := range []**Offer{&view.DrawOffer, &view.TakebackRequest, &view.PauseRequest, &view.ResumeRequest} {
+		if *offer == nil {
+			continue
+		}
+		if addressee, _ := g.OpponentOf((*offer).PlayerID); addressee != viewerID || viewerID == "" {
+			*offer = nil
+		}
+	}
+	return &view
+}
+
+// participant returns the seat occupied by playerID.
+func (g *Game) participant(playerID string) (*Participant, error) {
+	switch playerID {
+	case g.White.UserID:
+		return &g.White, nil
+	case g.Black.UserID:
+		return &g.Black, nil
+	}
+	return nil, errors.New("player is not part of this game")
 }
 
 // GetFEN returns the current board position
 func (g *Game) GetFEN() string {
+	if g.castling != nil {
+		return withCastlingField(g.internalGame.FEN(), g.castling.String())
+	}
 	return g.internalGame.FEN()
 }
 
-// IsGameOver checks if the game has ended (Checkmate, Draw, etc.)
-func (g *Game) IsGameOver() bool {
-	return g.internalGame.Outcome() != chess.NoOutcome
-}
-
-// GetResult returns the winner or draw reason
+// GetResult returns the result in PGN form ("1-0", "0-1", "1/2-1/2" or "*")
 func (g *Game) GetResult() string {
-	return g.internalGame.Outcome().String()
+	switch g.Outcome {
+	case OutcomeWhiteWins:
+		return string(chess.WhiteWon)
+	case OutcomeBlackWins:
+		return string(chess.BlackWon)
+	case OutcomeDraw:
+		return string(chess.Draw)
+	}
+	return string(chess.NoOutcome)
 }
 
-// RehydrateEngine reconstructs the chess engine from a FEN string.
-// This is essential when loading from a database/Redis.
-func (g *Game) RehydrateEngine(fen string) error {
-	f, err := chess.FEN(fen)
-	if err != nil {
+// Rehydrate reconstructs the chess engine after the game was loaded from a
+// database/Redis. History is replayed from the starting position so
+// repetitions and other history-dependent rules keep working. If cache holds
+// the engine this game was last saved with, it is reused instead.
+func (g *Game) Rehydrate(cache *EngineCache) error {
+	var engine *chess.Game
+	if cache != nil {
+		engine = cache.take(g)
+	}
+	if engine != nil {
+		g.internalGame = engine
+		if err := g.loadCastling(g.CurrentFEN); err != nil {
+			return err
+		}
+	} else if err := g.replayHistory(); err != nil {
 		return err
 	}
-	g.internalGame = chess.NewGame(f)
+
+	if g.State == "" {
+		g.backfillState()
+	}
 	return nil
 }
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// GiveTimeAmount is what a single "give time" adds to the opponent's clock.
+const GiveTimeAmount = 15 * time.Second
+
+// TimeGift is the payload of an EventTimeGiven event.
+type TimeGift struct {
+	FromID string        `json:"from_id"`
+	ToID   string        `json:"to_id"`
+	Amount time.Duration `json:"amount"`
+}
+
+// GiveTime adds GiveTimeAmount to the clock of playerID's opponent. It is
+// safe while that clock is running: think time is measured from UpdatedAt
+// and charged against TimeRemaining only when the move is made, so the
+// extra time simply pushes the flag back.
+func (g *Game) GiveTime(playerID string) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if g.Settings.IsCorrespondence() {
+		return errors.New("time cannot be given in correspondence games")
+	}
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	opponentID, _ := g.OpponentOf(playerID)
+	opponent, _ := g.participant(opponentID)
+
+	now := time.Now()
+	// A flag that has already fallen is not raised again
+	if len(g.History) > 0 && g.IsTurnOf(opponentID) {
+		allowance := g.clockOf(opponent).Rule(opponent.Stage).Allowance(opponent.TimeRemaining)
+		if now.Sub(g.UpdatedAt) >= allowance {
+			return errors.New("your opponent has already run out of time")
+		}
+	}
+
+	opponent.TimeRemaining += GiveTimeAmount
+	opponent.SyncTime()
+	g.Events = append(g.Events, GameEvent{
+		GameID:     g.ID,
+		Type:       EventTimeGiven,
+		Payload:    TimeGift{FromID: playerID, ToID: opponentID, Amount: GiveTimeAmount},
+		OccurredAt: now,
+	})
+	return nil
+}
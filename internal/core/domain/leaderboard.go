@@ -0,0 +1,17 @@
+package domain
+
+// LeaderboardEntry is one player's place on a category leaderboard.
+type LeaderboardEntry struct {
+	Rank     int     `json:"rank"` // 1-based
+	PlayerID string  `json:"player_id"`
+	Rating   float64 `json:"rating"`
+}
+
+// LeaderboardPage is one page of a category leaderboard, best rated first.
+type LeaderboardPage struct {
+	Category TimeCategory       `json:"category"`
+	Page     int                `json:"page"` // 1-based
+	PageSize int                `json:"page_size"`
+	Total    int                `json:"total"` // Players on the whole leaderboard
+	Entries  []LeaderboardEntry `json:"entries"`
+}
@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/notnil/chess"
+)
+
+// GameState is the lifecycle stage a game is in.
+type GameState string
+
+const (
+	StateCreated           GameState = "CREATED"             // Built by NewGame, not opened to the players yet
+	StateWaitingForPlayers GameState = "WAITING_FOR_PLAYERS" // Open, waiting for the first move
+	StateInProgress        GameState = "IN_PROGRESS"
+	StatePaused            GameState = "PAUSED"
+	StateFinished          GameState = "FINISHED" // Ended with a result (see Outcome/Termination)
+	StateAborted           GameState = "ABORTED"  // Ended without a result
+)
+
+// transitions lists, for every state, the states a game may move to next.
+var transitions = map[GameState][]GameState{
+	StateCreated:           {StateWaitingForPlayers, StateAborted},
+	StateWaitingForPlayers: {StateInProgress, StateFinished, StateAborted},
+	StateInProgress:        {StatePaused, StateFinished, StateAborted},
+	StatePaused:            {StateInProgress, StateFinished},
+	StateFinished:          {},
+	StateAborted:           {},
+}
+
+// Outcome is who won a finished game.
+type Outcome string
+
+const (
+	OutcomeWhiteWins Outcome = "WHITE_WINS"
+	OutcomeBlackWins Outcome = "BLACK_WINS"
+	OutcomeDraw      Outcome = "DRAW"
+)
+
+// Termination is how a finished game came to an end.
+type Termination string
+
+const (
+	TerminationCheckmate            Termination = "CHECKMATE"
+	TerminationResignation          Termination = "RESIGNATION"
+	TerminationTimeout              Termination = "TIMEOUT"
+	TerminationAgreement            Termination = "AGREEMENT"
+	TerminationStalemate            Termination = "STALEMATE"
+	TerminationRepetition           Termination = "REPETITION"      // Threefold, claimed by a player
+	TerminationFiftyMoveRule        Termination = "FIFTY_MOVE_RULE" // Claimed by a player
+	TerminationFivefoldRepetition   Termination = "FIVEFOLD_REPETITION"
+	TerminationSeventyFiveMoveRule  Termination = "SEVENTY_FIVE_MOVE_RULE"
+	TerminationInsufficientMaterial Termination = "INSUFFICIENT_MATERIAL"
+	TerminationAbandonment          Termination = "ABANDONED"
+
+	// Drawn: the flag fell, but the opponent could never have checkmated
+	TerminationTimeoutVsInsufficientMaterial Termination = "TIMEOUT_VS_INSUFFICIENT_MATERIAL"
+)
+
+// transition moves the game to the next lifecycle state, refusing moves the
+// lifecycle does not allow.
+func (g *Game) transition(to GameState) error {
+	for _, allowed := range transitions[g.State] {
+		if allowed == to {
+			g.State = to
+			return nil
+		}
+	}
+	return fmt.Errorf("game cannot go from %s to %s", g.State, to)
+}
+
+// Open makes a freshly created game available to its players.
+func (g *Game) Open() error {
+	return g.transition(StateWaitingForPlayers)
+}
+
+// IsGameOver reports whether the game has ended, with or without a result.
+func (g *Game) IsGameOver() bool {
+	return g.State == StateFinished || g.State == StateAborted
+}
+
+// checkPlayable returns an error unless players can currently act on the board.
+func (g *Game) checkPlayable() error {
+	switch g.State {
+	case StateWaitingForPlayers, StateInProgress:
+		return nil
+	case StateCreated:
+		return errors.New("game has not been opened yet")
+	case StatePaused:
+		return errors.New("game is paused")
+	}
+	return errors.New("game is already finished")
+}
+
+// checkOngoing returns an error unless the game has not ended yet. Unlike
+// checkPlayable it lets a paused game through, so players who will not
+// resume can still settle it by resignation or agreement.
+func (g *Game) checkOngoing() error {
+	if g.State == StatePaused {
+		return nil
+	}
+	return g.checkPlayable()
+}
+
+// finishGame records the result and closes the game. The winner is derived
+// from the outcome; WinnerID stays "" for a draw.
+func (g *Game) finishGame(outcome Outcome, termination Termination) error {
+	if err := g.transition(StateFinished); err != nil {
+		return err
+	}
+	g.Outcome = outcome
+	g.Termination = termination
+	switch outcome {
+	case OutcomeWhiteWins:
+		g.WinnerID = g.White.UserID
+	case OutcomeBlackWins:
+		g.WinnerID = g.Black.UserID
+	default:
+		g.WinnerID = ""
+	}
+	g.DrawOffer = nil
+	g.TakebackRequest = nil
+	g.premove = nil
+	g.ClaimableDraws = nil
+	g.PauseRequest = nil
+	g.ResumeRequest = nil
+	g.PausedAt = nil
+	return nil
+}
+
+// backfillState gives a game saved before games had a State the state it is
+// in. Such a game only knew it was finished; its result is the one on the
+// board, unless the caller already restored it (e.g. a flag that fell).
+func (g *Game) backfillState() {
+	switch {
+	case g.Outcome != "":
+		g.State = StateFinished
+	case g.internalGame.Outcome() != chess.NoOutcome:
+		g.State = StateInProgress
+		_ = g.finishGame(engineResult(g.internalGame.Outcome(), g.internalGame.Method()))
+	case len(g.History) > 0:
+		g.State = StateInProgress
+	default:
+		g.State = StateWaitingForPlayers
+	}
+}
+
+func (g *Game) abortGame() error {
+	if err := g.transition(StateAborted); err != nil {
+		return err
+	}
+	g.DrawOffer = nil
+	g.TakebackRequest = nil
+	g.premove = nil
+	g.ClaimableDraws = nil
+	g.PauseRequest = nil
+	g.ResumeRequest = nil
+	return nil
+}
+
+// winFor returns the outcome in which playerID is the winner.
+func (g *Game) winFor(playerID string) Outcome {
+	if playerID == g.White.UserID {
+		return OutcomeWhiteWins
+	}
+	return OutcomeBlackWins
+}
+
+// engineResult translates the outcome the chess engine detected on the board.
+func engineResult(outcome chess.Outcome, method chess.Method) (Outcome, Termination) {
+	var result Outcome
+	switch outcome {
+	case chess.WhiteWon:
+		result = OutcomeWhiteWins
+	case chess.BlackWon:
+		result = OutcomeBlackWins
+	default:
+		result = OutcomeDraw
+	}
+
+	var termination Termination
+	switch method {
+	case chess.Checkmate:
+		termination = TerminationCheckmate
+	case chess.Resignation:
+		termination = TerminationResignation
+	case chess.DrawOffer:
+		termination = TerminationAgreement
+	case chess.Stalemate:
+		termination = TerminationStalemate
+	case chess.ThreefoldRepetition:
+		termination = TerminationRepetition
+	case chess.FivefoldRepetition:
+		termination = TerminationFivefoldRepetition
+	case chess.FiftyMoveRule:
+		termination = TerminationFiftyMoveRule
+	case chess.SeventyFiveMoveRule:
+		termination = TerminationSeventyFiveMoveRule
+	case chess.InsufficientMaterial:
+		termination = TerminationInsufficientMaterial
+	}
+	return result, termination
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRehydrateBackfillsState(t *testing.T) {
+	tests := []struct {
+		name            string
+		moves           []string
+		outcome         Outcome // Result already restored by the repository
+		wantState       GameState
+		wantTermination Termination
+	}{
+		{"no move yet", nil, "", StateWaitingForPlayers, ""},
+		{"ongoing", []string{"e4", "e5"}, "", StateInProgress, ""},
+		{"ended on the board", []string{"f3", "e5", "g4", "Qh4#"}, "", StateFinished, TerminationCheckmate},
+		{"ended on time", []string{"e4", "e5"}, OutcomeWhiteWins, StateFinished, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, TimeControl{InitialTime: 300}, GameSetup{})
+			play(t, g, time.Second, tt.moves...)
+
+			// As saved before games had a State
+			g.State, g.Outcome, g.Termination, g.WinnerID = "", tt.outcome, "", ""
+			if err := g.Rehydrate(nil); err != nil {
+				t.Fatalf("Rehydrate() error = %v", err)
+			}
+			if g.State != tt.wantState || g.Termination != tt.wantTermination {
+				t.Errorf("State, Termination = %s, %q, want %s, %q", g.State, g.Termination, tt.wantState, tt.wantTermination)
+			}
+		})
+	}
+}
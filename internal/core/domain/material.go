@@ -0,0 +1,50 @@
+package domain
+
+import "github.com/notnil/chess"
+
+// canCheckmate reports whether color could still mate by some series of legal
+// moves, however unlikely, as the timeout rule asks. Only material that can
+// never mate counts as insufficient:
+//   - a lone king;
+//   - king and a single minor piece against a lone king;
+//   - bishops only, on either side, all standing on squares of one colour.
+func canCheckmate(board map[chess.Square]chess.Piece, color chess.Color) bool {
+	var own, other []chess.Square
+	for sq, p := range board {
+		switch p.Type() {
+		case chess.King:
+			continue
+		case chess.Pawn, chess.Rook, chess.Queen:
+			if p.Color() == color {
+				return true
+			}
+		}
+		if p.Color() == color {
+			own = append(own, sq)
+		} else {
+			other = append(other, sq)
+		}
+	}
+
+	switch {
+	case len(own) == 0:
+		return false
+	case len(own) == 1 && len(other) == 0:
+		return false
+	}
+
+	// Same-coloured bishops can neither mate nor be helped into a mate by
+	// bishops running on the same colour
+	shade := squareShade(own[0])
+	for _, sq := range append(own, other...) {
+		if board[sq].Type() != chess.Bishop || squareShade(sq) != shade {
+			return true
+		}
+	}
+	return false
+}
+
+// squareShade returns 0 for dark squares and 1 for light ones.
+func squareShade(sq chess.Square) int {
+	return (int(sq.File()) + int(sq.Rank())) % 2
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestCanCheckmate(t *testing.T) {
+	var (
+		darkC1  = square(2, 0)
+		lightF1 = square(5, 0)
+		lightC8 = square(2, 7)
+		darkF8  = square(5, 7)
+	)
+	kings := func(pieces map[chess.Square]chess.Piece) map[chess.Square]chess.Piece {
+		pieces[square(4, 0)] = chess.WhiteKing
+		pieces[square(4, 7)] = chess.BlackKing
+		return pieces
+	}
+
+	tests := []struct {
+		name  string
+		board map[chess.Square]chess.Piece
+		want  bool // for White
+	}{
+		{"lone king", kings(map[chess.Square]chess.Piece{square(3, 7): chess.BlackQueen}), false},
+		{"king and knight", kings(map[chess.Square]chess.Piece{square(6, 0): chess.WhiteKnight}), false},
+		{"king and bishop", kings(map[chess.Square]chess.Piece{lightF1: chess.WhiteBishop}), false},
+		{"king and pawn", kings(map[chess.Square]chess.Piece{square(0, 1): chess.WhitePawn}), true},
+		{"king and rook", kings(map[chess.Square]chess.Piece{square(7, 0): chess.WhiteRook}), true},
+		{"two knights", kings(map[chess.Square]chess.Piece{square(1, 0): chess.WhiteKnight, square(6, 0): chess.WhiteKnight}), true},
+		{"knight against knight", kings(map[chess.Square]chess.Piece{square(6, 0): chess.WhiteKnight, square(6, 7): chess.BlackKnight}), true},
+		{"bishop against a pawn", kings(map[chess.Square]chess.Piece{lightF1: chess.WhiteBishop, square(0, 6): chess.BlackPawn}), true},
+		{"bishops on one colour", kings(map[chess.Square]chess.Piece{lightF1: chess.WhiteBishop, square(7, 2): chess.WhiteBishop}), false},
+		{"bishops on both colours", kings(map[chess.Square]chess.Piece{lightF1: chess.WhiteBishop, darkC1: chess.WhiteBishop}), true},
+		{"bishops of one colour on each side", kings(map[chess.Square]chess.Piece{darkC1: chess.WhiteBishop, darkF8: chess.BlackBishop}), false},
+		{"bishops of opposite colours", kings(map[chess.Square]chess.Piece{darkC1: chess.WhiteBishop, lightC8: chess.BlackBishop}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canCheckmate(tt.board, chess.White); got != tt.want {
+				t.Errorf("canCheckmate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// MoveFormat is the notation a client submits a move in.
+type MoveFormat string
+
+const (
+	MoveFormatAuto MoveFormat = ""    // Detected from the move itself
+	MoveFormatSAN  MoveFormat = "SAN" // Standard Algebraic Notation e.g., "Nf3", "exd5", "O-O"
+	MoveFormatUCI  MoveFormat = "UCI" // e.g., "e2e4", "e7e8q"
+	MoveFormatLAN  MoveFormat = "LAN" // Long Algebraic Notation e.g., "e2-e4", "Ng1f3", "e7e8=Q"
+)
+
+var (
+	uciPattern = regexp.MustCompile(`^[a-h][1-8][a-h][1-8][qrbn]?$`)
+	lanPattern = regexp.MustCompile(`^[KQRBN]?[a-h][1-8][-x]?[a-h][1-8](=?[QRBNqrbn])?[+#]?$`)
+)
+
+// resolve checks the format and, when none was given, detects it from the
+// notation. Anything that is neither UCI nor LAN is taken as SAN.
+func (f MoveFormat) resolve(notation string) (MoveFormat, error) {
+	switch f {
+	case MoveFormatSAN, MoveFormatUCI, MoveFormatLAN:
+		return f, nil
+	case MoveFormatAuto:
+		switch {
+		case uciPattern.MatchString(notation):
+			return MoveFormatUCI, nil
+		case lanPattern.MatchString(notation):
+			return MoveFormatLAN, nil
+		}
+		return MoveFormatSAN, nil
+	}
+	return "", errors.New("unknown move format")
+}
+
+// decodeMove finds the legal move that notation describes in pos.
+func decodeMove(pos *chess.Position, notation string, format MoveFormat) (*chess.Move, error) {
+	switch format {
+	case MoveFormatUCI:
+		m, err := chess.UCINotation{}.Decode(pos, strings.ToLower(notation))
+		if err != nil {
+			return nil, err
+		}
+		// The UCI decoder does not check legality, nor tag captures and checks
+		for _, valid := range pos.ValidMoves() {
+			if valid.S1() == m.S1() && valid.S2() == m.S2() && valid.Promo() == m.Promo() {
+				return valid, nil
+			}
+		}
+		return nil, errors.New("no legal move matches " + notation)
+	case MoveFormatLAN:
+		// Clients differ on hyphens, capture marks, "=" and check marks, so compare without them
+		want := plainLAN(notation)
+		for _, m := range pos.ValidMoves() {
+			if plainLAN(chess.LongAlgebraicNotation{}.Encode(pos, m)) == want {
+				return m, nil
+			}
+		}
+		return nil, errors.New("no legal move matches " + notation)
+	}
+	return chess.AlgebraicNotation{}.Decode(pos, notation)
+}
+
+// plainLAN strips the optional punctuation from a long algebraic move.
+func plainLAN(notation string) string {
+	if _, ok := castleSide(notation); ok {
+		return strings.TrimRight(strings.ReplaceAll(notation, "0", "O"), "+#")
+	}
+	return strings.ToUpper(strings.NewReplacer("-", "", "x", "", "=", "", "+", "", "#", "").Replace(notation))
+}
+
+// castleSideOf recognises a Chess960 castling move: "O-O"/"O-O-O" in SAN or
+// LAN, or the king moving onto its own rook in UCI (the UCI_Chess960 convention).
+func (g *Game) castleSideOf(notation string, format MoveFormat) (int, bool) {
+	if format != MoveFormatUCI {
+		return castleSide(notation)
+	}
+
+	color := g.internalGame.Position().Turn()
+	ci, rank := colorIndex(color), backRankOf(color)
+	from := square(g.castling.kingFile[ci], rank).String()
+	for _, side := range []int{kingSide, queenSide} {
+		rook := g.castling.rooks[ci][side]
+		if rook >= 0 && strings.ToLower(notation) == from+square(rook, rank).String() {
+			return side, true
+		}
+	}
+	return 0, false
+}
+
+// checkSuffix returns "+" or "#" if the side to move in pos is in check or
+// mated, for moves the engine did not encode itself.
+func checkSuffix(pos *chess.Position) string {
+	turn := pos.Turn()
+	board := pos.Board().SquareMap()
+	for sq, p := range board {
+		if p.Type() != chess.King || p.Color() != turn {
+			continue
+		}
+		if !squareAttacked(board, sq, turn.Other()) {
+			return ""
+		}
+		if len(pos.ValidMoves()) == 0 {
+			return "#"
+		}
+		return "+"
+	}
+	return ""
+}
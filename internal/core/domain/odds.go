@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// OddsPiece is the piece a player gives away before the game starts.
+type OddsPiece string
+
+const (
+	OddsKnight OddsPiece = "KNIGHT" // Queen's knight (b1/b8)
+	OddsRook   OddsPiece = "ROOK"   // Queen's rook (a1/a8), taking its castling right along
+	OddsQueen  OddsPiece = "QUEEN"
+)
+
+// Odds is a material handicap: Side starts the game without Piece.
+type Odds struct {
+	Side  string    `json:"side"` // "white" or "black", the side giving the odds
+	Piece OddsPiece `json:"piece"`
+}
+
+// fen returns the standard starting position with the odds piece removed.
+func (o Odds) fen() (string, error) {
+	var file int
+	switch o.Piece {
+	case OddsKnight:
+		file = 1
+	case OddsRook:
+		file = 0
+	case OddsQueen:
+		file = 3
+	default:
+		return "", fmt.Errorf("unknown odds piece %q", o.Piece)
+	}
+
+	var rank int
+	var right string
+	switch o.Side {
+	case "white":
+		rank, right = 0, "Q"
+	case "black":
+		rank, right = 7, "q"
+	default:
+		return "", fmt.Errorf("odds side must be \"white\" or \"black\", got %q", o.Side)
+	}
+
+	board := chess.NewGame().Position().Board().SquareMap()
+	delete(board, square(file, rank))
+	castling := "KQkq"
+	if o.Piece == OddsRook {
+		castling = strings.Replace(castling, right, "", 1)
+	}
+	return fmt.Sprintf("%s w %s - 0 1", boardFEN(board), castling), nil
+}
+
+// giveTimeControl lets p play under a time control of their own instead of
+// the game's shared one.
+func (p *Participant) giveTimeControl(tc *TimeControl, shared TimeControl) error {
+	if tc == nil {
+		return nil
+	}
+	own := *tc
+	if err := own.normalize(); err != nil {
+		return err
+	}
+	if own.IsCorrespondence() != shared.IsCorrespondence() {
+		return fmt.Errorf("%w: per-color time controls cannot mix live and correspondence play", ErrInvalidSetup)
+	}
+	p.TimeControl = &own
+	p.TimeRemaining = time.Duration(own.InitialTime) * time.Second
+	return nil
+}
+
+// clockOf returns the time control p plays under: their own in a handicap
+// game, the game's Settings otherwise.
+func (g *Game) clockOf(p *Participant) *TimeControl {
+	if p.TimeControl != nil {
+		return p.TimeControl
+	}
+	return &g.Settings
+}
+
+// IsHandicap reports whether the game was played at odds, material or time,
+// and so should be kept out of ratings and statistics.
+func (g *Game) IsHandicap() bool {
+	return g.Odds != nil || g.White.TimeControl != nil || g.Black.TimeControl != nil
+}
@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// RequestPause asks the opponent to adjourn the game. If the opponent already
+// asked for the same, the game is paused straight away.
+func (g *Game) RequestPause(playerID string) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if g.CheckDeadline(time.Now()) {
+		// Too late to pause: the game ended on time instead
+		return nil
+	}
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	// Before that the first-move deadlines are running, which a pause would not stop
+	if g.State != StateInProgress || len(g.History) < 2 {
+		return errors.New("a game can only be paused once both players have moved")
+	}
+
+	if g.PauseRequest != nil {
+		if g.PauseRequest.PlayerID == playerID {
+			return errors.New("pause request already pending")
+		}
+		return g.AcceptPause(playerID)
+	}
+
+	g.PauseRequest = &Offer{PlayerID: playerID, Ply: len(g.History), CreatedAt: time.Now()}
+	return nil
+}
+
+// AcceptPause freezes both clocks until the game is resumed.
+// A flag that has already fallen is adjudicated first, in which case the game
+// ends rather than pausing: the pause must not stop a clock that has run out.
+func (g *Game) AcceptPause(playerID string) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if g.CheckDeadline(time.Now()) {
+		return nil
+	}
+	if err := g.checkRequestFor(g.PauseRequest, playerID); err != nil {
+		return err
+	}
+
+	if err := g.transition(StatePaused); err != nil {
+		return err
+	}
+	// UpdatedAt is left alone: it still marks when the running clock started,
+	// and resuming shifts it by the length of the pause
+	now := time.Now()
+	g.PausedAt = &now
+	g.PauseRequest = nil
+	return nil
+}
+
+// DeclinePause rejects the opponent's pending pause request.
+func (g *Game) DeclinePause(playerID string) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if err := g.checkRequestFor(g.PauseRequest, playerID); err != nil {
+		return err
+	}
+
+	g.PauseRequest = nil
+	return nil
+}
+
+// RequestResume asks the opponent to carry on with a paused game. If the
+// opponent already asked for the same, the game resumes straight away.
+func (g *Game) RequestResume(playerID string) error {
+	if err := g.checkPaused(); err != nil {
+		return err
+	}
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+
+	if g.ResumeRequest != nil {
+		if g.ResumeRequest.PlayerID == playerID {
+			return errors.New("resume request already pending")
+		}
+		return g.AcceptResume(playerID)
+	}
+
+	g.ResumeRequest = &Offer{PlayerID: playerID, Ply: len(g.History), CreatedAt: time.Now()}
+	return nil
+}
+
+// AcceptResume restarts the game where it was paused. The clock of the side
+// to move picks up exactly where it stopped: the pause is charged to nobody.
+func (g *Game) AcceptResume(playerID string) error {
+	if err := g.checkPaused(); err != nil {
+		return err
+	}
+	if err := g.checkRequestFor(g.ResumeRequest, playerID); err != nil {
+		return err
+	}
+
+	if err := g.transition(StateInProgress); err != nil {
+		return err
+	}
+	now := time.Now()
+	if g.PausedAt != nil {
+		g.UpdatedAt = g.UpdatedAt.Add(now.Sub(*g.PausedAt))
+	}
+	// Nobody can be abandoning a game while it is adjourned; the grace period starts over
+	for _, p := range []*Participant{&g.White, &g.Black} {
+		if p.DisconnectedAt != nil {
+			p.DisconnectedAt = &now
+		}
+	}
+	g.PausedAt = nil
+	g.ResumeRequest = nil
+	return nil
+}
+
+// DeclineResume rejects the opponent's pending resume request; the game stays paused.
+func (g *Game) DeclineResume(playerID string) error {
+	if err := g.checkPaused(); err != nil {
+		return err
+	}
+	if err := g.checkRequestFor(g.ResumeRequest, playerID); err != nil {
+		return err
+	}
+
+	g.ResumeRequest = nil
+	return nil
+}
+
+func (g *Game) checkPaused() error {
+	if g.State != StatePaused {
+		return errors.New("game is not paused")
+	}
+	return nil
+}
+
+// checkRequestFor verifies there is a request that playerID is allowed to answer.
+func (g *Game) checkRequestFor(request *Offer, playerID string) error {
+	if _, err := g.participant(playerID); err != nil {
+		return err
+	}
+	if request == nil || request.PlayerID == playerID {
+		return errors.New("no request to answer")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauseAfterFlagFell(t *testing.T) {
+	tests := []struct {
+		name  string
+		pause func(g *Game) error
+	}{
+		{"request", func(g *Game) error {
+			g.UpdatedAt = time.Now().Add(-time.Hour)
+			return g.RequestPause("black")
+		}},
+		{"accept", func(g *Game) error {
+			if err := g.RequestPause("black"); err != nil {
+				return err
+			}
+			// White's time runs out while the request is pending
+			g.UpdatedAt = time.Now().Add(-time.Hour)
+			return g.AcceptPause("white")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, TimeControl{InitialTime: 300}, GameSetup{})
+			play(t, g, time.Second, "e4", "e5")
+
+			if err := tt.pause(g); err != nil {
+				t.Fatalf("pause error = %v", err)
+			}
+			if g.State != StateFinished || g.Termination != TerminationTimeout || g.PausedAt != nil {
+				t.Errorf("game is %s (%s), paused at %v, want it finished on time", g.State, g.Termination, g.PausedAt)
+			}
+		})
+	}
+}
+
+func TestPauseWithTimeLeft(t *testing.T) {
+	g := newTestGame(t, TimeControl{InitialTime: 300}, GameSetup{})
+	play(t, g, time.Second, "e4", "e5")
+	if err := g.RequestPause("black"); err != nil {
+		t.Fatalf("RequestPause() error = %v", err)
+	}
+	if err := g.AcceptPause("white"); err != nil {
+		t.Fatalf("AcceptPause() error = %v", err)
+	}
+	if g.State != StatePaused {
+		t.Errorf("State = %s, want %s", g.State, StatePaused)
+	}
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pgnLineWidth is the maximum length of a movetext line, as the PGN standard asks.
+const pgnLineWidth = 80
+
+// PGN exports the game in Portable Game Notation. Games that do not start
+// from the standard position carry SetUp/FEN tags so they replay correctly.
+func (g *Game) PGN() string {
+	var b strings.Builder
+	tag := func(name, value string) {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		value = strings.ReplaceAll(value, `"`, `\"`)
+		fmt.Fprintf(&b, "[%s \"%s\"]\n", name, value)
+	}
+
+	event := "Casual game"
+	if g.Rated {
+		event = "Rated game"
+	}
+	tag("Event", event)
+	tag("Site", "ChesS-ma")
+	tag("Date", g.CreatedAt.UTC().Format("2006.01.02"))
+	tag("Round", "-")
+	tag("White", g.White.UserID)
+	tag("Black", g.Black.UserID)
+	tag("Result", g.GetResult())
+	if g.Variant == VariantChess960 {
+		tag("Variant", "Chess960")
+	}
+	if g.StartFEN != "" && g.StartFEN != StandardFEN {
+		tag("SetUp", "1")
+		tag("FEN", g.StartFEN)
+	}
+	tag("TimeControl", g.Settings.PGNTag())
+	if g.Settings.IsDelay() {
+		// Non-standard, TimeControl alone cannot tell a delay from an increment
+		tag("ClockMode", string(g.Settings.Mode))
+	}
+	// Non-standard tags, so handicap games can be told apart
+	if g.White.TimeControl != nil {
+		tag("WhiteTimeControl", g.White.TimeControl.PGNTag())
+	}
+	if g.Black.TimeControl != nil {
+		tag("BlackTimeControl", g.Black.TimeControl.PGNTag())
+	}
+	if g.Odds != nil {
+		tag("Odds", g.Odds.Side+" "+strings.ToLower(string(g.Odds.Piece)))
+	}
+	if g.Termination != "" {
+		tag("Termination", string(g.Termination))
+	}
+	b.WriteString("\n")
+
+	// Move numbering follows the starting position's side to move and move number
+	moveNumber, blackToMove := 1, false
+	if fields := strings.Fields(g.StartFEN); len(fields) == 6 {
+		blackToMove = fields[1] == "b"
+		if n, err := strconv.Atoi(fields[5]); err == nil && n > 0 {
+			moveNumber = n
+		}
+	}
+
+	tokens := make([]string, 0, len(g.History)*2+1)
+	for i, m := range g.History {
+		switch {
+		case !blackToMove:
+			tokens = append(tokens, fmt.Sprintf("%d.", moveNumber))
+		case i == 0:
+			tokens = append(tokens, fmt.Sprintf("%d...", moveNumber))
+		}
+		tokens = append(tokens, m.Notation)
+		if blackToMove {
+			moveNumber++
+		}
+		blackToMove = !blackToMove
+	}
+	tokens = append(tokens, g.GetResult())
+
+	line := 0
+	for i, t := range tokens {
+		if i > 0 {
+			if line+1+len(t) > pgnLineWidth {
+				b.WriteString("\n")
+				line = 0
+			} else {
+				b.WriteString(" ")
+				line++
+			}
+		}
+		b.WriteString(t)
+		line += len(t)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
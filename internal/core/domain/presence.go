@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// SetPresence records whether playerID currently has a connection to the game.
+func (g *Game) SetPresence(playerID string, online bool) error {
+	if g.IsGameOver() {
+		return errors.New("game is already finished")
+	}
+	p, err := g.participant(playerID)
+	if err != nil {
+		return err
+	}
+
+	if online {
+		p.Status = StatusOnline
+		p.DisconnectedAt = nil
+		return nil
+	}
+	if p.Status != StatusOffline {
+		now := time.Now()
+		p.Status = StatusOffline
+		p.DisconnectedAt = &now
+	}
+	return nil
+}
+
+// AbandonmentDeadline returns the earliest moment a disconnected player's
+// opponent may claim the game. The second value is false when nobody is
+// away or the game cannot be abandoned (correspondence, not started, paused).
+func (g *Game) AbandonmentDeadline() (time.Time, bool) {
+	var deadline time.Time
+	for _, p := range []*Participant{&g.White, &g.Black} {
+		at, ok := g.abandonedAt(p)
+		if ok && (deadline.IsZero() || at.Before(deadline)) {
+			deadline = at
+		}
+	}
+	return deadline, !deadline.IsZero()
+}
+
+// AbandonedBy returns the player who has been away past the grace period by now.
+func (g *Game) AbandonedBy(now time.Time) (string, bool) {
+	for _, p := range []*Participant{&g.White, &g.Black} {
+		if at, ok := g.abandonedAt(p); ok && !now.Before(at) {
+			return p.UserID, true
+		}
+	}
+	return "", false
+}
+
+// ClaimAbandonment ends the game once playerID's opponent has stayed
+// disconnected past the grace period: as a win for playerID, or as a draw if
+// they would rather call it one.
+func (g *Game) ClaimAbandonment(playerID string, draw bool) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	opponentID, err := g.OpponentOf(playerID)
+	if err != nil {
+		return err
+	}
+	opponent, _ := g.participant(opponentID)
+	now := time.Now()
+	if at, ok := g.abandonedAt(opponent); !ok || now.Before(at) {
+		return errors.New("your opponent has not abandoned the game")
+	}
+
+	g.UpdatedAt = now
+	if draw {
+		return g.finishGame(OutcomeDraw, TerminationAbandonment)
+	}
+	return g.finishGame(g.winFor(playerID), TerminationAbandonment)
+}
+
+// abandonedAt is when p's absence turns into abandonment. Until both players
+// have moved, the first-move deadline deals with absent players instead.
+func (g *Game) abandonedAt(p *Participant) (time.Time, bool) {
+	if g.checkPlayable() != nil || len(g.History) < 2 || p.DisconnectedAt == nil {
+		return time.Time{}, false
+	}
+	grace, ok := g.Settings.AbandonmentGrace()
+	if !ok {
+		return time.Time{}, false
+	}
+	return p.DisconnectedAt.Add(grace), true
+}
@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// Glicko-2 system constants.
+const (
+	DefaultRating     = 1500.0
+	DefaultRD         = 350.0 // Rating deviation of a player who has never played
+	DefaultVolatility = 0.06
+
+	glickoScale = 173.7178 // Converts between the Glicko and Glicko-2 scales
+	glickoTau   = 0.5      // Constrains how fast volatility changes
+	glickoEps   = 0.000001 // Convergence tolerance of the volatility iteration
+	minRD       = 45.0     // Keeps established ratings from freezing completely
+)
+
+// Rating is a player's Glicko-2 rating in one time category. Games goes up by
+// exactly one with every update, so it doubles as the rating's version.
+type Rating struct {
+	PlayerID   string       `json:"player_id"`
+	Category   TimeCategory `json:"category"`
+	Rating     float64      `json:"rating"`
+	RD         float64      `json:"rd"`
+	Volatility float64      `json:"volatility"`
+	Games      int          `json:"games"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// NewRating returns the starting rating of a player new to category.
+func NewRating(playerID string, category TimeCategory) Rating {
+	return Rating{
+		PlayerID:   playerID,
+		Category:   category,
+		Rating:     DefaultRating,
+		RD:         DefaultRD,
+		Volatility: DefaultVolatility,
+	}
+}
+
+// RatingChange records how one game moved a player's rating.
+type RatingChange struct {
+	PlayerID string  `json:"player_id"`
+	Before   float64 `json:"before"`
+	After    float64 `json:"after"`
+	Delta    float64 `json:"delta"`
+	RDAfter  float64 `json:"rd_after"`
+}
+
+// GameRatings is the rating outcome of a rated game.
+type GameRatings struct {
+	Category TimeCategory `json:"category"`
+	White    RatingChange `json:"white"`
+	Black    RatingChange `json:"black"`
+}
+
+// RatingCategory is the category the game's ratings are kept in.
+func (g *Game) RatingCategory() TimeCategory {
+	return g.Settings.Category()
+}
+
+// ApplyRatings computes both players' new ratings from the result of a
+// finished rated game, records the change on the game and returns the new
+// ratings for the caller to persist.
+func (g *Game) ApplyRatings(white, black Rating, now time.Time) (Rating, Rating, bool) {
+	if !g.Rated || g.State != StateFinished || g.Ratings != nil {
+		return white, black, false
+	}
+
+	newWhite := g.RatingAfter(white, black, now)
+	newBlack := g.RatingAfter(black, white, now)
+	g.Ratings = &GameRatings{
+		Category: g.RatingCategory(),
+		White:    change(white, newWhite),
+		Black:    change(black, newBlack),
+	}
+	return newWhite, newBlack, true
+}
+
+// RatingAfter returns player's rating after this game against opponent, whose
+// rating is taken as it stood before the game.
+func (g *Game) RatingAfter(player, opponent Rating, now time.Time) Rating {
+	var score float64
+	switch {
+	case g.Outcome == OutcomeDraw:
+		score = 0.5
+	case g.WinnerID == player.PlayerID:
+		score = 1
+	}
+	return player.update(opponent, score, now)
+}
+
+// RecordRatingChange replaces the rating change recorded for one player, e.g.
+// once their rating had to be recomputed from a newer value.
+func (g *Game) RecordRatingChange(before, after Rating) {
+	if g.Ratings == nil {
+		return
+	}
+	switch before.PlayerID {
+	case g.White.UserID:
+		g.Ratings.White = change(before, after)
+	case g.Black.UserID:
+		g.Ratings.Black = change(before, after)
+	}
+}
+
+func change(before, after Rating) RatingChange {
+	return RatingChange{
+		PlayerID: before.PlayerID,
+		Before:   before.Rating,
+		After:    after.Rating,
+		Delta:    after.Rating - before.Rating,
+		RDAfter:  after.RD,
+	}
+}
+
+// update returns r after a single game against opponent in which r scored
+// score (1, 0.5 or 0), following Glickman's Glicko-2 paper with the game as
+// its own rating period.
+func (r Rating) update(opponent Rating, score float64, now time.Time) Rating {
+	mu, phi := (r.Rating-DefaultRating)/glickoScale, r.RD/glickoScale
+	muJ, phiJ := (opponent.Rating-DefaultRating)/glickoScale, opponent.RD/glickoScale
+
+	g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+	e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+	v := 1 / (g * g * e * (1 - e))
+	delta := v * g * (score - e)
+
+	sigma := newVolatility(phi, r.Volatility, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*g*(score-e)
+
+	r.Rating = newMu*glickoScale + DefaultRating
+	r.RD = math.Min(math.Max(newPhi*glickoScale, minRD), DefaultRD)
+	r.Volatility = sigma
+	r.Games++
+	r.UpdatedAt = now
+	return r
+}
+
+// newVolatility solves for the new volatility with the Illinois algorithm
+// (step 5 of the Glicko-2 paper).
+func newVolatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoEps {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
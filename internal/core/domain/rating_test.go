@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewVolatility(t *testing.T) {
+	// The worked example of Glickman's Glicko-2 paper
+	got := newVolatility(1.1513, 0.06, 1.7785, -0.4834)
+	if math.Abs(got-0.05999) > 0.00001 {
+		t.Errorf("newVolatility() = %.5f, want 0.05999", got)
+	}
+
+	// A result far from the expected one raises the volatility
+	if got := newVolatility(0.2, 0.06, 1.0, 3.0); got <= 0.06 {
+		t.Errorf("newVolatility() = %.5f after an upset, want above 0.06", got)
+	}
+}
+
+func TestRatingUpdate(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rating := func(value, rd float64) Rating {
+		return Rating{PlayerID: "p", Rating: value, RD: rd, Volatility: DefaultVolatility, Games: 7}
+	}
+
+	tests := []struct {
+		name     string
+		player   Rating
+		opponent Rating
+		score    float64
+		want     float64
+		wantRD   float64
+	}{
+		{"new players, win", rating(1500, 350), rating(1500, 350), 1, 1662.31, 290.32},
+		{"new players, loss", rating(1500, 350), rating(1500, 350), 0, 1337.69, 290.32},
+		{"new players, draw", rating(1500, 350), rating(1500, 350), 0.5, 1500, 290.32},
+		{"favourite held to a draw", rating(1700, 50), rating(1500, 50), 0.5, 1696.24, 50.68},
+		{"deviation kept above the floor", rating(1500, 30), rating(1500, 30), 0.5, 1500, minRD},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.player.update(tt.opponent, tt.score, now)
+			if math.Abs(got.Rating-tt.want) > 0.01 {
+				t.Errorf("Rating = %.2f, want %.2f", got.Rating, tt.want)
+			}
+			if math.Abs(got.RD-tt.wantRD) > 0.01 {
+				t.Errorf("RD = %.2f, want %.2f", got.RD, tt.wantRD)
+			}
+			if got.Games != tt.player.Games+1 || !got.UpdatedAt.Equal(now) {
+				t.Errorf("Games, UpdatedAt = %d, %v, want %d, %v", got.Games, got.UpdatedAt, tt.player.Games+1, now)
+			}
+		})
+	}
+}
+
+func TestApplyRatings(t *testing.T) {
+	now := time.Now()
+	finished := func(outcome Outcome, winner string) *Game {
+		return &Game{
+			White:    Participant{UserID: "white"},
+			Black:    Participant{UserID: "black"},
+			Rated:    true,
+			State:    StateFinished,
+			Outcome:  outcome,
+			WinnerID: winner,
+		}
+	}
+
+	// sign reports which way a rating moved
+	sign := func(before, after Rating) int {
+		switch {
+		case after.Rating > before.Rating:
+			return 1
+		case after.Rating < before.Rating:
+			return -1
+		}
+		return 0
+	}
+
+	tests := []struct {
+		name       string
+		game       *Game
+		whiteMoved int // Direction each rating moves in
+		blackMoved int
+	}{
+		{"white wins", finished(OutcomeWhiteWins, "white"), 1, -1},
+		{"black wins", finished(OutcomeBlackWins, "black"), -1, 1},
+		{"draw between equals", finished(OutcomeDraw, ""), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			white, black := NewRating("white", ""), NewRating("black", "")
+			newWhite, newBlack, ok := tt.game.ApplyRatings(white, black, now)
+			if !ok {
+				t.Fatal("ApplyRatings() did not rate a finished rated game")
+			}
+			if sign(white, newWhite) != tt.whiteMoved {
+				t.Errorf("white went from %.2f to %.2f", white.Rating, newWhite.Rating)
+			}
+			if sign(black, newBlack) != tt.blackMoved {
+				t.Errorf("black went from %.2f to %.2f", black.Rating, newBlack.Rating)
+			}
+			if r := tt.game.Ratings; r == nil || r.White.After != newWhite.Rating || r.Black.Delta != newBlack.Rating-black.Rating {
+				t.Errorf("Ratings = %+v, does not match the new ratings", r)
+			}
+
+			// A game is only ever rated once
+			if _, _, ok := tt.game.ApplyRatings(newWhite, newBlack, now); ok {
+				t.Error("ApplyRatings() rated the same game twice")
+			}
+		})
+	}
+}
+
+func TestApplyRatingsSkipsUnratedGames(t *testing.T) {
+	tests := []struct {
+		name string
+		game *Game
+	}{
+		{"casual", &Game{State: StateFinished, Outcome: OutcomeDraw}},
+		{"still playing", &Game{Rated: true, State: StateInProgress}},
+		{"aborted", &Game{Rated: true, State: StateAborted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, ok := tt.game.ApplyRatings(NewRating("white", ""), NewRating("black", ""), time.Now()); ok {
+				t.Error("ApplyRatings() rated the game")
+			}
+			if tt.game.Ratings != nil {
+				t.Errorf("Ratings = %+v, want nil", tt.game.Ratings)
+			}
+		})
+	}
+}
+
+func TestPGNEventTag(t *testing.T) {
+	tests := []struct {
+		rated bool
+		want  string
+	}{
+		{false, `[Event "Casual game"]`},
+		{true, `[Event "Rated game"]`},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, TimeControl{InitialTime: 300}, GameSetup{Rated: tt.rated})
+		if pgn := g.PGN(); !strings.Contains(pgn, tt.want) {
+			t.Errorf("PGN() of a game rated=%v has no %s tag:\n%s", tt.rated, tt.want, pgn)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// StandardFEN is the starting position of a regular game.
+const StandardFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// ErrInvalidSetup wraps every error caused by the options a game was created with.
+var ErrInvalidSetup = errors.New("invalid game setup")
+
+type Variant string
+
+const (
+	VariantStandard Variant = "standard"
+	VariantChess960 Variant = "chess960" // Fischer Random
+)
+
+// GameSetup holds the optional choices made when a game is created.
+type GameSetup struct {
+	Variant Variant
+	// Chess960Position picks one of the 960 starting positions (0-959); nil draws one at random
+	Chess960Position *int
+	// StartFEN starts a standard game from a custom position; "" means the usual setup
+	StartFEN string
+	// Odds removes a piece from one side's standard starting position
+	Odds *Odds
+	// WhiteSettings and BlackSettings give one side a time control of its own
+	// instead of the game's; nil keeps the shared one
+	WhiteSettings *TimeControl
+	BlackSettings *TimeControl
+	// Rated games update the players' ratings once finished
+	Rated bool
+}
+
+// apply decides the variant and starting position of a new game.
+func (s GameSetup) apply(g *Game) error {
+	switch s.Variant {
+	case "", VariantStandard:
+		if s.Chess960Position != nil {
+			return fmt.Errorf("%w: a chess960 position needs the chess960 variant", ErrInvalidSetup)
+		}
+		g.Variant = VariantStandard
+		g.StartFEN = StandardFEN
+		if s.StartFEN != "" {
+			if err := validateStartFEN(s.StartFEN); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidSetup, err)
+			}
+			g.StartFEN = strings.TrimSpace(s.StartFEN)
+		}
+		if s.Odds != nil {
+			if s.StartFEN != "" {
+				return fmt.Errorf("%w: odds cannot be combined with a starting FEN", ErrInvalidSetup)
+			}
+			fen, err := s.Odds.fen()
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidSetup, err)
+			}
+			odds := *s.Odds
+			g.Odds = &odds
+			g.StartFEN = fen
+		}
+	case VariantChess960:
+		if s.StartFEN != "" {
+			return fmt.Errorf("%w: a starting FEN cannot be combined with chess960", ErrInvalidSetup)
+		}
+		if s.Odds != nil {
+			return fmt.Errorf("%w: odds cannot be combined with chess960", ErrInvalidSetup)
+		}
+		n := rand.Intn(960)
+		if s.Chess960Position != nil {
+			n = *s.Chess960Position
+		}
+		fen, err := chess960FEN(n)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSetup, err)
+		}
+		g.Variant = VariantChess960
+		g.Chess960Position = &n
+		g.StartFEN = fen
+	default:
+		return fmt.Errorf("%w: unknown variant %q", ErrInvalidSetup, s.Variant)
+	}
+
+	if err := g.White.giveTimeControl(s.WhiteSettings, g.Settings); err != nil {
+		return err
+	}
+	if err := g.Black.giveTimeControl(s.BlackSettings, g.Settings); err != nil {
+		return err
+	}
+
+	if s.Rated {
+		// Ratings only compare like with like
+		if g.IsHandicap() {
+			return fmt.Errorf("%w: handicap games cannot be rated", ErrInvalidSetup)
+		}
+		if s.StartFEN != "" {
+			return fmt.Errorf("%w: games from a custom position cannot be rated", ErrInvalidSetup)
+		}
+		g.Rated = true
+		// A rated result has to stand as played
+		g.Settings.DisallowTakebacks = true
+	}
+	return nil
+}
+
+// validateStartFEN checks that fen describes a position a game can start from.
+func validateStartFEN(fen string) error {
+	opt, err := chess.FEN(strings.TrimSpace(fen))
+	if err != nil {
+		return fmt.Errorf("invalid starting FEN: %v", err)
+	}
+	engine := chess.NewGame(opt)
+	pos := engine.Position()
+	board := pos.Board().SquareMap()
+
+	kings := map[chess.Color]chess.Square{}
+	for sq, p := range board {
+		switch p.Type() {
+		case chess.King:
+			if _, dup := kings[p.Color()]; dup {
+				return errors.New("starting FEN must have exactly one king per side")
+			}
+			kings[p.Color()] = sq
+		case chess.Pawn:
+			if rank := int(sq) / 8; rank == 0 || rank == 7 {
+				return errors.New("starting FEN has a pawn on the first or last rank")
+			}
+		}
+	}
+	if len(kings) != 2 {
+		return errors.New("starting FEN must have exactly one king per side")
+	}
+	// The engine trusts the rights, and would castle with a rook that is not there
+	if err := checkCastlingRights(strings.Fields(fen)[2], board); err != nil {
+		return err
+	}
+
+	// The side that just "moved" cannot have left its own king in check
+	waiting := pos.Turn().Other()
+	if squareAttacked(board, kings[waiting], pos.Turn()) {
+		return errors.New("starting FEN leaves the side not to move in check")
+	}
+	if len(engine.ValidMoves()) == 0 {
+		return errors.New("starting FEN has no legal moves, the game would already be over")
+	}
+	return nil
+}
+
+// castlingHomes gives, for each standard castling right, the color and the
+// files of the king and rook it needs on their back rank.
+var castlingHomes = map[rune]struct {
+	color              chess.Color
+	kingFile, rookFile int
+}{
+	'K': {chess.White, 4, 7},
+	'Q': {chess.White, 4, 0},
+	'k': {chess.Black, 4, 7},
+	'q': {chess.Black, 4, 0},
+}
+
+// checkCastlingRights verifies that every right in a standard FEN castling
+// field still has its king and rook on their starting squares.
+func checkCastlingRights(field string, board map[chess.Square]chess.Piece) error {
+	if field == "-" {
+		return nil
+	}
+	for _, r := range field {
+		home, ok := castlingHomes[r]
+		if !ok {
+			return fmt.Errorf("invalid castling rights %q", field)
+		}
+		rank := backRankOf(home.color)
+		king, rook := board[square(home.kingFile, rank)], board[square(home.rookFile, rank)]
+		if king.Type() != chess.King || king.Color() != home.color || rook.Type() != chess.Rook || rook.Color() != home.color {
+			return fmt.Errorf("castling right %q needs the king and rook on their starting squares", r)
+		}
+	}
+	return nil
+}
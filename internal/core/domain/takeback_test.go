@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestGame starts an open standard game between "white" and "black".
+func newTestGame(t *testing.T, tc TimeControl, setup GameSetup) *Game {
+	t.Helper()
+	g, err := NewGame("white", "black", tc, setup)
+	if err != nil {
+		t.Fatalf("NewGame() error = %v", err)
+	}
+	if err := g.Open(); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return g
+}
+
+// play makes each move in turn, charging the mover think of clock time.
+func play(t *testing.T, g *Game, think time.Duration, moves ...string) {
+	t.Helper()
+	for _, move := range moves {
+		mover, _ := g.sideToMove()
+		g.UpdatedAt = time.Now().Add(-think)
+		if err := g.MakeMove(mover.UserID, move, MoveFormatAuto); err != nil {
+			t.Fatalf("MakeMove(%q) error = %v", move, err)
+		}
+	}
+}
+
+func TestAcceptTakebackRestoresClocks(t *testing.T) {
+	tests := []struct {
+		name      string
+		requester string
+		wantPlies int // History left once the takeback is accepted
+	}{
+		{"own last move", "black", 3},
+		{"move already replied to", "white", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, TimeControl{InitialTime: 300, Increment: 2}, GameSetup{})
+			play(t, g, 10*time.Second, "e4", "e5", "Nf3", "Nc6")
+			before := append([]Move(nil), g.History...)
+
+			opponent, _ := g.OpponentOf(tt.requester)
+			if err := g.RequestTakeback(tt.requester); err != nil {
+				t.Fatalf("RequestTakeback() error = %v", err)
+			}
+			if err := g.AcceptTakeback(opponent); err != nil {
+				t.Fatalf("AcceptTakeback() error = %v", err)
+			}
+
+			if len(g.History) != tt.wantPlies {
+				t.Fatalf("len(History) = %d, want %d", len(g.History), tt.wantPlies)
+			}
+			if g.GetFEN() != before[tt.wantPlies-1].FENAfter {
+				t.Errorf("GetFEN() = %q, want %q", g.GetFEN(), before[tt.wantPlies-1].FENAfter)
+			}
+			// Each side is back on the clock it had before its first undone
+			// move, or keeps the one its last remaining move left it with
+			want := map[string]time.Duration{}
+			for _, m := range before[:tt.wantPlies] {
+				want[m.PlayerID] = m.ClockAfter
+			}
+			for i := len(before) - 1; i >= tt.wantPlies; i-- {
+				want[before[i].PlayerID] = before[i].ClockBefore
+			}
+			if g.White.TimeRemaining != want["white"] {
+				t.Errorf("White.TimeRemaining = %v, want %v", g.White.TimeRemaining, want["white"])
+			}
+			if g.Black.TimeRemaining != want["black"] {
+				t.Errorf("Black.TimeRemaining = %v, want %v", g.Black.TimeRemaining, want["black"])
+			}
+			if g.TakebackRequest != nil {
+				t.Errorf("TakebackRequest = %+v, want nil", g.TakebackRequest)
+			}
+		})
+	}
+}
+
+func TestAcceptTakebackRestoresStage(t *testing.T) {
+	tc := TimeControl{Stages: []TimeStage{{Moves: 2, Time: 60}, {Time: 30}}}
+	g := newTestGame(t, tc, GameSetup{})
+	play(t, g, time.Second, "e4", "e5", "Nf3", "Nc6")
+	if g.White.Stage != 1 {
+		t.Fatalf("White.Stage = %d after the first stage's moves, want 1", g.White.Stage)
+	}
+	clockBefore := g.History[2].ClockBefore
+
+	if err := g.RequestTakeback("white"); err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if err := g.AcceptTakeback("black"); err != nil {
+		t.Fatalf("AcceptTakeback() error = %v", err)
+	}
+
+	// Undoing the move that completed the stage takes its extra time back too
+	if g.White.Stage != 0 {
+		t.Errorf("White.Stage = %d, want 0", g.White.Stage)
+	}
+	if g.White.TimeRemaining != clockBefore {
+		t.Errorf("White.TimeRemaining = %v, want %v", g.White.TimeRemaining, clockBefore)
+	}
+}
+
+func TestRequestTakebackDisallowed(t *testing.T) {
+	g := newTestGame(t, TimeControl{InitialTime: 300, DisallowTakebacks: true}, GameSetup{})
+	play(t, g, time.Second, "e4")
+	if err := g.RequestTakeback("white"); err == nil {
+		t.Error("RequestTakeback() error = nil in a game without takebacks")
+	}
+}
@@ -1,10 +1,116 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultFirstMoveTimeout applies when a TimeControl does not set FirstMoveTimeout.
+const DefaultFirstMoveTimeout = 30 * time.Second
 
 type TimeControl struct {
-	InitialTime int `json:"initial_time"` // total seconds
-	Increment   int `json:"increment"`    // Seconds added per move
+	InitialTime int       `json:"initial_time"` // total seconds
+	Increment   int       `json:"increment"`    // Seconds added per move (FISCHER)
+	Mode        ClockMode `json:"mode"`         // FISCHER (default), BRONSTEIN or SIMPLE_DELAY
+	Delay       int       `json:"delay"`        // Delay in seconds (BRONSTEIN, SIMPLE_DELAY)
+	// Stages describe a classical multi-period control (e.g. 40/90 + 30/SD +30s).
+	// When set, the first stage replaces InitialTime and Increment.
+	Stages []TimeStage `json:"stages,omitempty"`
+	// DaysPerMove makes this a correspondence game: every move gets a fresh
+	// deadline of that many days instead of a running clock.
+	DaysPerMove int `json:"days_per_move,omitempty"`
+
+	DisallowTakebacks bool `json:"disallow_takebacks"` // e.g. for rated games
+	FirstMoveTimeout  int  `json:"first_move_timeout"` // Seconds each player has for their first move before the game is aborted
+}
+
+// TimeStage is one period of a staged time control. Each player enters the
+// next stage on their own once they have completed the moves of the current
+// one, and the stage's time is added to their clock.
+type TimeStage struct {
+	Moves     int `json:"moves"`     // Moves to play in this stage, 0 for the rest of the game
+	Time      int `json:"time"`      // Seconds added when the stage starts
+	Increment int `json:"increment"` // Per-move bonus in seconds during the stage (increment or delay, per Mode)
+}
+
+// FirstMoveTimeLimit returns how long a player may take over their first move.
+func (tc TimeControl) FirstMoveTimeLimit() time.Duration {
+	if tc.FirstMoveTimeout > 0 {
+		return time.Duration(tc.FirstMoveTimeout) * time.Second
+	}
+	if tc.IsCorrespondence() {
+		return tc.moveDeadline()
+	}
+	return DefaultFirstMoveTimeout
+}
+
+// IsCorrespondence reports whether the game is played at days per move.
+func (tc TimeControl) IsCorrespondence() bool {
+	return tc.DaysPerMove > 0
+}
+
+// moveDeadline is the time a correspondence player has for each move.
+func (tc TimeControl) moveDeadline() time.Duration {
+	return time.Duration(tc.DaysPerMove) * 24 * time.Hour
+}
+
+// TimeCategory groups time controls by how long a game is expected to last.
+type TimeCategory string
+
+const (
+	CategoryBullet         TimeCategory = "bullet"
+	CategoryBlitz          TimeCategory = "blitz"
+	CategoryRapid          TimeCategory = "rapid"
+	CategoryClassical      TimeCategory = "classical"
+	CategoryCorrespondence TimeCategory = "correspondence"
+)
+
+// TimeCategories lists every category, fastest first.
+var TimeCategories = []TimeCategory{CategoryBullet, CategoryBlitz, CategoryRapid, CategoryClassical, CategoryCorrespondence}
+
+// ParseTimeCategory checks that s names a time category.
+func ParseTimeCategory(s string) (TimeCategory, error) {
+	for _, c := range TimeCategories {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown time category %q", s)
+}
+
+// Category classifies the time control by its estimated duration per player:
+// the initial time plus 40 moves' worth of increment or delay.
+func (tc TimeControl) Category() TimeCategory {
+	if tc.IsCorrespondence() {
+		return CategoryCorrespondence
+	}
+	estimate := tc.InitialTime + 40*(tc.Increment+tc.Delay)
+	switch {
+	case estimate < 180:
+		return CategoryBullet
+	case estimate < 480:
+		return CategoryBlitz
+	case estimate < 1500:
+		return CategoryRapid
+	}
+	return CategoryClassical
+}
+
+// AbandonmentGrace is how long a player may stay disconnected before their
+// opponent can claim the game. There is none in correspondence play.
+func (tc TimeControl) AbandonmentGrace() (time.Duration, bool) {
+	switch tc.Category() {
+	case CategoryBullet:
+		return 15 * time.Second, true
+	case CategoryBlitz:
+		return 30 * time.Second, true
+	case CategoryRapid:
+		return time.Minute, true
+	case CategoryClassical:
+		return 2 * time.Minute, true
+	}
+	return 0, false
 }
 
 type PlayerStatus string
@@ -17,10 +123,19 @@ const (
 type Participant struct {
 	UserID string       `json:"user_id"`
 	Status PlayerStatus `json:"status"`
+	// When the player's last connection dropped, while offline
+	DisconnectedAt *time.Time `json:"disconnected_at,omitempty"`
 	// We keep this unexported (lowercase) or tagged with "-" so it stays out of JSON
 	TimeRemaining time.Duration `json:"time_remaining_raw"`
 	// This is what the frontend will see
 	TimeFormatted float64 `json:"time_remaining"`
+	// How many moves this player had made when they last offered a draw (-1 if
+	// never), used to allow one offer per move of their own
+	LastDrawOfferMove int `json:"last_draw_offer_move"`
+	// Overrides the game's Settings for this player in a handicap game
+	TimeControl *TimeControl `json:"time_control,omitempty"`
+	// Index into the Stages of the player's time control of the stage they are in
+	Stage int `json:"stage"`
 }
 
 // SyncTime updates the exported float field from the internal duration
@@ -28,9 +143,50 @@ func (p *Participant) SyncTime() {
 	p.TimeFormatted = p.TimeRemaining.Seconds()
 }
 
+// Move is one ply of the game, complete enough to render the game without
+// replaying it.
 type Move struct {
+	Ply       int       `json:"ply"` // 1-based
 	FENBefore string    `json:"fen_before"`
-	Notation  string    `json:"notation"`
+	FENAfter  string    `json:"fen_after"`
+	Notation  string    `json:"notation"` // Canonical SAN, whatever format the move was sent in
+	UCI       string    `json:"uci"`      // e.g., "e2e4"; Chess960 castling is king-takes-rook ("e1h1")
 	PlayerID  string    `json:"player_id"`
 	Timestamp time.Time `json:"timestamp"`
+	// Mover's remaining time before this move was charged, so a takeback can restore it
+	ClockBefore time.Duration `json:"clock_before"`
+	ClockAfter  time.Duration `json:"clock_after"` // After think time, increment and any new stage
+	ThinkTime   time.Duration `json:"think_time"`  // Charged to the clock: 0 for the first move and premoves
+
+	Captured  string `json:"captured,omitempty"` // Piece type taken ("p", "n", "b", "r", "q")
+	Check     bool   `json:"check"`              // Also set on checkmate
+	Checkmate bool   `json:"checkmate"`
+	Castle    string `json:"castle,omitempty"` // "O-O" or "O-O-O"
+	EnPassant bool   `json:"en_passant"`
+	Promotion string `json:"promotion,omitempty"` // Piece type promoted to ("q", "r", "b", "n")
+}
+
+// markSAN sets the check and castling markers that the SAN already spells out.
+func (m *Move) markSAN() {
+	m.Checkmate = strings.HasSuffix(m.Notation, "#")
+	m.Check = m.Checkmate || strings.HasSuffix(m.Notation, "+")
+	switch san := strings.TrimRight(m.Notation, "+#"); san {
+	case "O-O", "O-O-O":
+		m.Castle = san
+	}
+}
+
+// Offer is a pending proposal from one player that the opponent has to answer.
+type Offer struct {
+	PlayerID  string    `json:"player_id"`
+	Ply       int       `json:"ply"` // len(History) when the offer was made
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Premove is a move queued by the player waiting for their opponent, played
+// automatically as soon as it is their turn.
+type Premove struct {
+	PlayerID string     `json:"player_id"`
+	Notation string     `json:"notation"`
+	Format   MoveFormat `json:"format,omitempty"`
 }
@@ -0,0 +1,27 @@
+package domain
+
+import "errors"
+
+// ErrVersionConflict wraps the error a repository returns when a game or a
+// rating was changed by someone else between being loaded and being updated.
+var ErrVersionConflict = errors.New("modified concurrently")
+
+// Clone returns a deep copy of the game, engine included, that can be changed
+// without affecting the original.
+func (g *Game) Clone() *Game {
+	c := *g
+	c.History = make([]Move, len(g.History))
+	copy(c.History, g.History)
+	c.Events = append([]GameEvent(nil), g.Events...)
+	c.ClaimableDraws = append([]Termination(nil), g.ClaimableDraws...)
+
+	// Everything else behind a pointer is only ever replaced, never changed in place
+	if g.internalGame != nil {
+		c.internalGame = g.internalGame.Clone()
+	}
+	if g.castling != nil {
+		castling := *g.castling
+		c.castling = &castling
+	}
+	return &c
+}
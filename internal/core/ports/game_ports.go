@@ -2,25 +2,111 @@ package ports
 
 import (
 	"context"
+	"time"
+
 	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
 	"github.com/google/uuid"
 )
 
 type GameRepository interface {
+	// Save stores a new game as is.
 	Save(ctx context.Context, game *domain.Game) error
 	FindByID(ctx context.Context, id uuid.UUID) (*domain.Game, error)
+	// Update stores game only if the stored copy is still at game.Version, and
+	// bumps the version. Otherwise it fails with an error wrapping
+	// domain.ErrVersionConflict.
 	Update(ctx context.Context, game *domain.Game) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	// FindByPlayer returns the games playerID is seated in that are not over yet.
+	FindByPlayer(ctx context.Context, playerID string) ([]*domain.Game, error)
+	// FindActive returns every game that is not over yet.
+	FindActive(ctx context.Context) ([]*domain.Game, error)
+	// FindFinished returns the games that are over but have not been archived
+	// yet.
+	FindFinished(ctx context.Context) ([]*domain.Game, error)
+	// Evict removes an archived game from live storage once keepFor has
+	// passed. Until then it can still be loaded, but FindFinished skips it.
+	Evict(ctx context.Context, id uuid.UUID, keepFor time.Duration) error
 }
 
 // New Archive Port for MongoDB
 type GameArchiveRepository interface {
+	// Archive stores a finished game. Archiving the same game again overwrites it.
 	Archive(ctx context.Context, game *domain.Game) error
+	// LatestRatings returns, for every player with a rated game in category
+	// archived since activeSince, the rating their latest such game left them with.
+	LatestRatings(ctx context.Context, category domain.TimeCategory, activeSince time.Time) ([]domain.Rating, error)
+}
+
+// RatingRepository stores each player's rating per time category.
+type RatingRepository interface {
+	// Get returns the player's rating in category, or domain.NewRating if they have none yet.
+	Get(ctx context.Context, playerID string, category domain.TimeCategory) (domain.Rating, error)
+	// Save stores rating only if it was computed from the stored one, i.e. the
+	// stored rating still has rating.Games-1 games. Otherwise it fails with an
+	// error wrapping domain.ErrVersionConflict.
+	Save(ctx context.Context, rating domain.Rating) error
+}
+
+// LeaderboardRepository keeps the players of each time category ordered by rating.
+type LeaderboardRepository interface {
+	// Record puts players on their category's leaderboard at their current rating.
+	Record(ctx context.Context, ratings ...domain.Rating) error
+	// Page returns limit entries starting at offset (0-based), best rated first, and the total count.
+	Page(ctx context.Context, category domain.TimeCategory, offset, limit int) ([]domain.LeaderboardEntry, int, error)
+	// RemoveInactive drops players whose last rated game is older than before.
+	RemoveInactive(ctx context.Context, category domain.TimeCategory, before time.Time) error
+	// Replace swaps a category's whole leaderboard for ratings.
+	Replace(ctx context.Context, category domain.TimeCategory, ratings []domain.Rating) error
 }
 
 type GameService interface {
-	CreateGame(ctx context.Context, whiteId, blackId string, tc domain.TimeControl) (*domain.Game, error)
-	// Updated to take playerID for turn validation
-	MakeMove(ctx context.Context, gameId uuid.UUID, playerID string, moveNotation string) (*domain.Game, error)
+	CreateGame(ctx context.Context, whiteId, blackId string, tc domain.TimeControl, setup domain.GameSetup) (*domain.Game, error)
+	// Updated to take playerID for turn validation; format may be MoveFormatAuto to detect SAN, UCI or LAN
+	MakeMove(ctx context.Context, gameId uuid.UUID, playerID string, moveNotation string, format domain.MoveFormat) (*domain.Game, error)
 	GetGame(ctx context.Context, gameId uuid.UUID) (*domain.Game, error)
+	Resign(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	Abort(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	OfferDraw(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	AcceptDraw(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	DeclineDraw(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	// ClaimDraw ends the game on a threefold repetition or fifty-move claim by the side to move.
+	ClaimDraw(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	RequestTakeback(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	AcceptTakeback(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	DeclineTakeback(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	// SetPremove queues a move for playerID that is played right after the opponent's next move.
+	SetPremove(ctx context.Context, gameId uuid.UUID, playerID string, moveNotation string, format domain.MoveFormat) (*domain.Game, error)
+	CancelPremove(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	// SetPresence records a player connecting to or disconnecting from the game.
+	SetPresence(ctx context.Context, gameId uuid.UUID, playerID string, online bool) (*domain.Game, error)
+	// ClaimAbandonment ends the game as a win (or a draw, if draw is set) for
+	// playerID once their opponent stayed disconnected past the grace period.
+	ClaimAbandonment(ctx context.Context, gameId uuid.UUID, playerID string, draw bool) (*domain.Game, error)
+	// GiveTime adds domain.GiveTimeAmount to the clock of playerID's opponent.
+	GiveTime(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	// RequestPause and RequestResume need the opponent's acceptance; while
+	// paused both clocks are frozen.
+	RequestPause(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	AcceptPause(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	DeclinePause(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	RequestResume(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	AcceptResume(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	DeclineResume(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error)
+	// GamesAwaitingMove lists the games in which it is playerID's turn, most urgent first.
+	GamesAwaitingMove(ctx context.Context, playerID string) ([]*domain.Game, error)
+	// Subscribe registers a listener for every change made to a game, whether
+	// through a request (HTTP or WebSocket) or by the service on its own (e.g.
+	// a flag falling or a first-move timeout).
+	Subscribe(listener func(event domain.GameEvent))
+}
+
+type LeaderboardService interface {
+	// Leaderboard returns one page (1-based) of a category's leaderboard.
+	Leaderboard(ctx context.Context, category domain.TimeCategory, page, pageSize int) (*domain.LeaderboardPage, error)
+	// Rebuild recomputes every leaderboard from the rated games in the archive.
+	Rebuild(ctx context.Context) error
+	// RunInactivityPruner drops inactive players from the leaderboards every
+	// interval, until ctx is cancelled.
+	RunInactivityPruner(ctx context.Context, interval time.Duration)
 }
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+	"github.com/google/uuid"
+)
+
+// deadlineScheduler keeps one timer per active game, armed for the next
+// deadline deadlineOf finds in the game, e.g. the side to move running out of
+// time or a first move that never came. When it fires, onDeadline is called
+// with the game ID so the service can act on the game.
+type deadlineScheduler struct {
+	mu         sync.Mutex
+	timers     map[uuid.UUID]scheduledTimer
+	generation uint64 // Tells a timer apart from the ones that replaced it
+	deadlineOf func(game *domain.Game) (time.Time, bool)
+	onDeadline func(gameId uuid.UUID)
+}
+
+type scheduledTimer struct {
+	timer      *time.Timer
+	generation uint64
+}
+
+func newDeadlineScheduler(deadlineOf func(game *domain.Game) (time.Time, bool), onDeadline func(gameId uuid.UUID)) *deadlineScheduler {
+	return &deadlineScheduler{
+		timers:     make(map[uuid.UUID]scheduledTimer),
+		deadlineOf: deadlineOf,
+		onDeadline: onDeadline,
+	}
+}
+
+// track (re)arms the timer of a game from its current state, or drops it if
+// nothing can end the game on its own anymore.
+func (ds *deadlineScheduler) track(game *domain.Game) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if scheduled, ok := ds.timers[game.ID]; ok {
+		scheduled.timer.Stop()
+		delete(ds.timers, game.ID)
+	}
+
+	deadline, pending := ds.deadlineOf(game)
+	if !pending {
+		return
+	}
+
+	// The callback cannot run fire before the entry is stored, as fire
+	// needs the lock held here
+	ds.generation++
+	gameId, generation := game.ID, ds.generation
+	ds.timers[gameId] = scheduledTimer{
+		timer:      time.AfterFunc(time.Until(deadline), func() { ds.fire(gameId, generation) }),
+		generation: generation,
+	}
+}
+
+func (ds *deadlineScheduler) fire(gameId uuid.UUID, generation uint64) {
+	ds.mu.Lock()
+	// A newer timer may have replaced this one while it was firing
+	if scheduled, ok := ds.timers[gameId]; !ok || scheduled.generation != generation {
+		ds.mu.Unlock()
+		return
+	}
+	delete(ds.timers, gameId)
+	ds.mu.Unlock()
+
+	ds.onDeadline(gameId)
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+	"github.com/google/uuid"
+)
+
+func TestDeadlineSchedulerFiresPastDeadline(t *testing.T) {
+	fired := make(chan uuid.UUID, 1)
+	ds := newDeadlineScheduler(
+		func(*domain.Game) (time.Time, bool) { return time.Now().Add(-time.Second), true },
+		func(gameId uuid.UUID) { fired <- gameId },
+	)
+
+	game := &domain.Game{ID: uuid.New()}
+	ds.track(game)
+
+	select {
+	case id := <-fired:
+		if id != game.ID {
+			t.Errorf("fired for %s, want %s", id, game.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a deadline already past never fired")
+	}
+}
+
+func TestDeadlineSchedulerRearm(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+	fired := make(chan uuid.UUID, 2)
+	ds := newDeadlineScheduler(
+		func(*domain.Game) (time.Time, bool) { return deadline, true },
+		func(gameId uuid.UUID) { fired <- gameId },
+	)
+
+	game := &domain.Game{ID: uuid.New()}
+	ds.track(game)
+	// Re-arming replaces the hour-long timer with one that is already due
+	deadline = time.Now()
+	ds.track(game)
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("the re-armed deadline never fired")
+	}
+	select {
+	case <-fired:
+		t.Error("the deadline fired twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -2,92 +2,522 @@ package services
 
 import (
 	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
 	"github.com/ChesS-ma/gameplay_service/internal/core/ports"
 	"github.com/google/uuid"
 )
 
-type service struct {
+// GameService implements ports.GameService. It also runs the background
+// sweepers, which are started by main rather than exposed to the handlers.
+type GameService struct {
 	repo    ports.GameRepository        // Usually Redis
 	archive ports.GameArchiveRepository // Usually MongoDB
+	ratings ports.RatingRepository      // Glicko-2 ratings per time category
+	board   ports.LeaderboardRepository // Usually Redis
+	timers  *deadlineScheduler          // Ends games whose clock or first-move time runs out while nobody is moving
+	grace   *deadlineScheduler          // Tells players when a disconnected opponent's grace period is over
+
+	mu        sync.RWMutex
+	listeners []func(event domain.GameEvent)
 }
 
-func NewService(repo ports.GameRepository, archive ports.GameArchiveRepository) ports.GameService {
-	return &service{
+func NewService(repo ports.GameRepository, archive ports.GameArchiveRepository, ratings ports.RatingRepository, board ports.LeaderboardRepository) *GameService {
+	s := &GameService{
 		repo:    repo,
 		archive: archive,
+		ratings: ratings,
+		board:   board,
 	}
+	s.timers = newDeadlineScheduler((*domain.Game).NextDeadline, s.handleDeadline)
+	s.grace = newDeadlineScheduler((*domain.Game).AbandonmentDeadline, s.handleAbandonment)
+	return s
 }
 
-func (s *service) CreateGame(ctx context.Context, whiteId, blackId string, tc domain.TimeControl) (*domain.Game, error) {
-	newGame := domain.NewGame(whiteId, blackId, tc)
+func (s *GameService) CreateGame(ctx context.Context, whiteId, blackId string, tc domain.TimeControl, setup domain.GameSetup) (*domain.Game, error) {
+	newGame, err := domain.NewGame(whiteId, blackId, tc, setup)
+	if err != nil {
+		return nil, err
+	}
+	if err := newGame.Open(); err != nil {
+		return nil, err
+	}
 
 	if err := s.repo.Save(ctx, newGame); err != nil {
 		return nil, err
 	}
+	// Arms the first-move deadline, in case nobody ever loads the game
+	s.track(newGame)
 	return newGame, nil
 }
 
-//	func (s *service) MakeMove(ctx context.Context, gameId uuid.UUID, playerID string, moveNotation string) (*domain.Game, error) {
-//		// 1. Fetch current state from Redis
-//		game, err := s.repo.FindByID(ctx, gameId)
-//		if err != nil {
-//			return nil, err
-//		}
-//
-//		// 2. Apply move (Business Logic inside Domain)
-//		if err := game.MakeMove(playerID, moveNotation); err != nil {
-//			return nil, err
-//		}
-//
-//		// 3. Orchestrate Persistence
-//		if game.IsGameOver() {
-//			// Store in MongoDB permanently
-//			_ = s.archive.Archive(ctx, game)
-//			// Clean up Redis
-//			_ = s.repo.Delete(ctx, gameId)
-//		} else {
-//			// Update Redis for next move
-//			if err := s.repo.Update(ctx, game); err != nil {
-//				return nil, err
-//			}
-//		}
-//
-//		return game, nil
-//	}
-func (s *service) MakeMove(ctx context.Context, gameId uuid.UUID, playerID string, moveNotation string) (*domain.Game, error) {
-	game, err := s.repo.FindByID(ctx, gameId)
+func (s *GameService) MakeMove(ctx context.Context, gameId uuid.UUID, playerID string, moveNotation string, format domain.MoveFormat) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.MakeMove(playerID, moveNotation, format)
+	})
+}
+
+func (s *GameService) GetGame(ctx context.Context, gameId uuid.UUID) (*domain.Game, error) {
+	game, err := s.loadGame(ctx, gameId)
 	if err != nil {
 		return nil, err
 	}
+	// Re-arm the deadline timer, e.g. for games that were live before a restart
+	s.track(game)
+	return game, nil
+}
+
+func (s *GameService) Resign(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.Resign(playerID)
+	})
+}
+
+func (s *GameService) Abort(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.Abort(playerID)
+	})
+}
+
+func (s *GameService) OfferDraw(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.OfferDraw(playerID)
+	})
+}
+
+func (s *GameService) AcceptDraw(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.AcceptDraw(playerID)
+	})
+}
+
+func (s *GameService) DeclineDraw(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.DeclineDraw(playerID)
+	})
+}
+
+func (s *GameService) ClaimDraw(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.ClaimDraw(playerID)
+	})
+}
+
+func (s *GameService) RequestTakeback(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.RequestTakeback(playerID)
+	})
+}
+
+func (s *GameService) AcceptTakeback(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.AcceptTakeback(playerID)
+	})
+}
+
+func (s *GameService) DeclineTakeback(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.DeclineTakeback(playerID)
+	})
+}
+
+func (s *GameService) SetPremove(ctx context.Context, gameId uuid.UUID, playerID string, moveNotation string, format domain.MoveFormat) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.SetPremove(playerID, moveNotation, format)
+	})
+}
+
+func (s *GameService) CancelPremove(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.CancelPremove(playerID)
+	})
+}
+
+func (s *GameService) SetPresence(ctx context.Context, gameId uuid.UUID, playerID string, online bool) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.SetPresence(playerID, online)
+	})
+}
+
+func (s *GameService) ClaimAbandonment(ctx context.Context, gameId uuid.UUID, playerID string, draw bool) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.ClaimAbandonment(playerID, draw)
+	})
+}
+
+func (s *GameService) GiveTime(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.GiveTime(playerID)
+	})
+}
+
+func (s *GameService) RequestPause(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return pauseResult(s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.RequestPause(playerID)
+	}))
+}
+
+func (s *GameService) AcceptPause(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return pauseResult(s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.AcceptPause(playerID)
+	}))
+}
 
-	// --- REHYDRATE ENGINE ---
-	fen := game.CurrentFEN
-	if fen == "" {
-		fen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+// pauseResult rejects a pause that found the flag already fallen: the domain
+// ended the game on time instead, which is saved and announced like any other
+// result, but the caller asked for a pause and did not get one.
+func pauseResult(game *domain.Game, err error) (*domain.Game, error) {
+	if err == nil && game.IsGameOver() {
+		return nil, errors.New("the game ended on time before it could be paused")
 	}
-	game.RehydrateEngine(fen) // Rebuild the chess logic engine
-	// ------------------------
+	return game, err
+}
+
+func (s *GameService) DeclinePause(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.DeclinePause(playerID)
+	})
+}
+
+func (s *GameService) RequestResume(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.RequestResume(playerID)
+	})
+}
 
-	if err := game.MakeMove(playerID, moveNotation); err != nil {
+func (s *GameService) AcceptResume(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.AcceptResume(playerID)
+	})
+}
+
+func (s *GameService) DeclineResume(ctx context.Context, gameId uuid.UUID, playerID string) (*domain.Game, error) {
+	return s.updateGame(ctx, gameId, func(game *domain.Game) error {
+		return game.DeclineResume(playerID)
+	})
+}
+
+func (s *GameService) GamesAwaitingMove(ctx context.Context, playerID string) ([]*domain.Game, error) {
+	games, err := s.repo.FindByPlayer(ctx, playerID)
+	if err != nil {
 		return nil, err
 	}
 
-	// ... persistence logic (Update Redis or Archive) ...
-	s.repo.Save(ctx, game)
-	return game, nil
+	waiting := make([]*domain.Game, 0, len(games))
+	for _, game := range games {
+		if game.IsTurnOf(playerID) {
+			waiting = append(waiting, game)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool {
+		di, _ := waiting[i].NextDeadline()
+		dj, _ := waiting[j].NextDeadline()
+		return di.Before(dj)
+	})
+	return waiting, nil
+}
+
+// RunDeadlineSweeper adjudicates active games whose deadline has passed and
+// re-arms the timers of the others, right away and then every interval,
+// until ctx is cancelled.
+func (s *GameService) RunDeadlineSweeper(ctx context.Context, interval time.Duration) {
+	// The timers only live in memory, so the first sweep re-arms them after a restart
+	s.sweepDeadlines(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweepDeadlines(ctx)
+		}
+	}
 }
-func (s *service) GetGame(ctx context.Context, gameId uuid.UUID) (*domain.Game, error) {
-	game, err := s.repo.FindByID(ctx, gameId)
+
+// sweepDeadlines adjudicates active games whose deadline has passed and
+// re-arms the timers of the others. The in-memory timers cover the same
+// ground, but they do not survive a restart, and are only armed for games
+// this instance has loaded.
+func (s *GameService) sweepDeadlines(ctx context.Context) {
+	games, err := s.repo.FindActive(ctx)
 	if err != nil {
-		return nil, err
+		log.Printf("Deadline sweep failed: %v", err)
+		return
 	}
 
-	// Use the stored FEN to rebuild the engine
-	if game.CurrentFEN == "" {
-		game.CurrentFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	now := time.Now()
+	for _, game := range games {
+		if deadline, pending := game.NextDeadline(); pending && !now.Before(deadline) {
+			s.handleDeadline(game.ID)
+			continue
+		}
+		s.timers.track(game)
+		// A grace period that already ran out has been offered, or will be on reconnect
+		if deadline, pending := game.AbandonmentDeadline(); pending && now.Before(deadline) {
+			s.grace.track(game)
+		}
 	}
-	game.RehydrateEngine(game.CurrentFEN)
+}
 
+// track re-arms the game's timers from its current state.
+func (s *GameService) track(game *domain.Game) {
+	s.timers.track(game)
+	s.grace.track(game)
+}
+
+// loadGame fetches a game. The repository hands it back with its chess engine
+// already rebuilt from the move history.
+func (s *GameService) loadGame(ctx context.Context, gameId uuid.UUID) (*domain.Game, error) {
+	return s.repo.FindByID(ctx, gameId)
+}
+
+// maxUpdateAttempts bounds how often updateGame starts over after losing a
+// race with another update of the same game.
+const maxUpdateAttempts = 3
+
+// updateGame is the read-modify-write cycle shared by every game action:
+// load the game, let the domain apply the change, persist it and tell the
+// listeners. The write only goes through if nobody else saved the game in
+// between; otherwise the change is applied again to the newer version.
+func (s *GameService) updateGame(ctx context.Context, gameId uuid.UUID, apply func(game *domain.Game) error) (*domain.Game, error) {
+	var game *domain.Game
+	var wasOver bool
+	for attempt := 1; ; attempt++ {
+		var err error
+		game, err = s.loadGame(ctx, gameId)
+		if err != nil {
+			return nil, err
+		}
+
+		wasOver = game.IsGameOver()
+		if err := apply(game); err != nil {
+			return nil, err
+		}
+
+		err = s.repo.Update(ctx, game)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, domain.ErrVersionConflict) || attempt == maxUpdateAttempts {
+			return nil, err
+		}
+	}
+	s.track(game)
+
+	if !wasOver && game.IsGameOver() {
+		// Rated only once the result is stored, so a lost race cannot rate a game twice
+		if s.rate(ctx, game) {
+			if err := s.repo.Update(ctx, game); err != nil {
+				log.Printf("Saving ratings of game %s failed: %v", game.ID, err)
+			}
+		}
+		// Archived in the background on a copy, the caller still has the game to report
+		go s.finalize(context.Background(), game.Clone())
+		s.publish(domain.EventGameFinished, game)
+		return game, nil
+	}
+	s.publish(domain.EventGameUpdated, game)
 	return game, nil
 }
+
+// rate updates both players' ratings from a rated game that just finished,
+// recording the change on the game, and reports whether it did so and the
+// game needs saving again. A failure is logged: the result of the game stands
+// either way.
+func (s *GameService) rate(ctx context.Context, game *domain.Game) bool {
+	if !game.Rated || game.State != domain.StateFinished {
+		return false
+	}
+	category := game.RatingCategory()
+	white, err := s.ratings.Get(ctx, game.White.UserID, category)
+	if err != nil {
+		log.Printf("Loading ratings for game %s failed: %v", game.ID, err)
+		return false
+	}
+	black, err := s.ratings.Get(ctx, game.Black.UserID, category)
+	if err != nil {
+		log.Printf("Loading ratings for game %s failed: %v", game.ID, err)
+		return false
+	}
+
+	now := time.Now()
+	newWhite, newBlack, changed := game.ApplyRatings(white, black, now)
+	if !changed {
+		return false
+	}
+	// Each player is saved on their own, against the opponent's rating from
+	// before the game, so a race on one player never rates the other twice
+	newWhite, err = s.saveRating(ctx, game, white, newWhite, black, now)
+	if err != nil {
+		log.Printf("Saving ratings for game %s failed: %v", game.ID, err)
+		game.Ratings = nil // Not applied, so not shown either
+		return false
+	}
+	newBlack, err = s.saveRating(ctx, game, black, newBlack, white, now)
+	if err != nil {
+		// White's rating is saved already and cannot be taken back, so the ratings stay on the game
+		log.Printf("Saving the rating of %s for game %s failed: %v", black.PlayerID, game.ID, err)
+		return true
+	}
+
+	// The leaderboards can always be rebuilt from the archive, so a failure here is not fatal
+	if err := s.board.Record(ctx, newWhite, newBlack); err != nil {
+		log.Printf("Updating leaderboards for game %s failed: %v", game.ID, err)
+	}
+	return true
+}
+
+// saveRating stores a player's new rating. If the player was rated by another
+// game since before was read, the update is redone on their newer rating.
+func (s *GameService) saveRating(ctx context.Context, game *domain.Game, before, after, opponent domain.Rating, now time.Time) (domain.Rating, error) {
+	for attempt := 1; ; attempt++ {
+		err := s.ratings.Save(ctx, after)
+		if err == nil {
+			game.RecordRatingChange(before, after)
+			return after, nil
+		}
+		if !errors.Is(err, domain.ErrVersionConflict) || attempt == maxUpdateAttempts {
+			return domain.Rating{}, err
+		}
+
+		before, err = s.ratings.Get(ctx, before.PlayerID, before.Category)
+		if err != nil {
+			return domain.Rating{}, err
+		}
+		after = game.RatingAfter(before, opponent, now)
+	}
+}
+
+// Archiving is retried a few times, backing off, before a game is left for
+// the archive sweeper to pick up.
+const (
+	archiveAttempts = 3
+	archiveBackoff  = 500 * time.Millisecond
+)
+
+// finishedGameRetention is how long an archived game stays in live storage,
+// so players who reconnect or refresh can still see the result and export it.
+const finishedGameRetention = 24 * time.Hour
+
+// finalize moves a game that reached a terminal state from live storage to
+// the archive. The live copy is only evicted once the archive has it, so a
+// game is never lost in between.
+func (s *GameService) finalize(ctx context.Context, game *domain.Game) {
+	for attempt := 1; ; attempt++ {
+		err := s.archive.Archive(ctx, game)
+		if err == nil {
+			break
+		}
+		if attempt == archiveAttempts {
+			log.Printf("Archiving game %s failed, leaving it for the sweeper: %v", game.ID, err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(attempt) * archiveBackoff):
+		}
+	}
+
+	if err := s.repo.Evict(ctx, game.ID, finishedGameRetention); err != nil {
+		// Archiving is idempotent, so the sweeper just goes over it again
+		log.Printf("Evicting archived game %s failed: %v", game.ID, err)
+	}
+}
+
+// RunArchiveSweeper archives and evicts finished games left in live storage,
+// every interval, until ctx is cancelled.
+func (s *GameService) RunArchiveSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweepFinished(ctx)
+		}
+	}
+}
+
+// sweepFinished archives the games that are over but still in live storage:
+// their archiving failed, or the server stopped before it could happen.
+func (s *GameService) sweepFinished(ctx context.Context) {
+	games, err := s.repo.FindFinished(ctx)
+	if err != nil {
+		log.Printf("Archive sweep failed: %v", err)
+		return
+	}
+
+	for _, game := range games {
+		s.finalize(ctx, game)
+	}
+}
+
+func (s *GameService) Subscribe(listener func(event domain.GameEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *GameService) publish(eventType domain.GameEventType, game *domain.Game) {
+	event := domain.GameEvent{
+		GameID:     game.ID,
+		Type:       eventType,
+		Payload:    game,
+		OccurredAt: time.Now(),
+	}
+
+	// Listeners are called without the lock, so a slow one cannot hold up Subscribe
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// errDeadlineNotReached stops handleDeadline's update when there is nothing to do.
+var errDeadlineNotReached = errors.New("deadline not reached")
+
+// handleDeadline is called by the deadline scheduler when a game should have
+// ended on time: a flag fell or a first move never came.
+func (s *GameService) handleDeadline(gameId uuid.UUID) {
+	_, err := s.updateGame(context.Background(), gameId, func(game *domain.Game) error {
+		if !game.CheckDeadline(time.Now()) {
+			// The game moved on since the timer was armed, follow it
+			s.track(game)
+			return errDeadlineNotReached
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDeadlineNotReached) {
+		log.Printf("Deadline check failed for game %s: %v", gameId, err)
+	}
+}
+
+// handleAbandonment is called by the grace scheduler when a disconnected
+// player's grace period runs out, so their opponent can be offered the claim.
+func (s *GameService) handleAbandonment(gameId uuid.UUID) {
+	game, err := s.loadGame(context.Background(), gameId)
+	if err != nil {
+		log.Printf("Abandonment check failed for game %s: %v", gameId, err)
+		return
+	}
+
+	if _, abandoned := game.AbandonedBy(time.Now()); !abandoned {
+		// The player came back, or the game moved on, since the timer was armed
+		s.track(game)
+		return
+	}
+	s.publish(domain.EventAbandonmentClaimable, game)
+}
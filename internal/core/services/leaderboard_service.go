@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ChesS-ma/gameplay_service/internal/core/domain"
+	"github.com/ChesS-ma/gameplay_service/internal/core/ports"
+)
+
+// MaxLeaderboardPageSize caps how many entries a single page can ask for.
+const MaxLeaderboardPageSize = 100
+
+type leaderboardService struct {
+	board         ports.LeaderboardRepository // Usually Redis
+	archive       ports.GameArchiveRepository // Usually MongoDB, the source of truth for rebuilds
+	inactiveAfter time.Duration               // Players without a rated game for this long drop off
+}
+
+func NewLeaderboardService(board ports.LeaderboardRepository, archive ports.GameArchiveRepository, inactiveAfter time.Duration) ports.LeaderboardService {
+	return &leaderboardService{
+		board:         board,
+		archive:       archive,
+		inactiveAfter: inactiveAfter,
+	}
+}
+
+func (s *leaderboardService) Leaderboard(ctx context.Context, category domain.TimeCategory, page, pageSize int) (*domain.LeaderboardPage, error) {
+	if page < 1 {
+		return nil, errors.New("page must be 1 or more")
+	}
+	if pageSize < 1 || pageSize > MaxLeaderboardPageSize {
+		return nil, fmt.Errorf("page size must be between 1 and %d", MaxLeaderboardPageSize)
+	}
+
+	offset := (page - 1) * pageSize
+	entries, total, err := s.board.Page(ctx, category, offset, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.LeaderboardPage{
+		Category: category,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+		Entries:  entries,
+	}, nil
+}
+
+func (s *leaderboardService) Rebuild(ctx context.Context) error {
+	activeSince := time.Now().Add(-s.inactiveAfter)
+	for _, category := range domain.TimeCategories {
+		ratings, err := s.archive.LatestRatings(ctx, category, activeSince)
+		if err != nil {
+			return err
+		}
+		if err := s.board.Replace(ctx, category, ratings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *leaderboardService) RunInactivityPruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.pruneInactive(ctx)
+		}
+	}
+}
+
+func (s *leaderboardService) pruneInactive(ctx context.Context) {
+	before := time.Now().Add(-s.inactiveAfter)
+	for _, category := range domain.TimeCategories {
+		if err := s.board.RemoveInactive(ctx, category, before); err != nil {
+			log.Printf("Pruning the %s leaderboard failed: %v", category, err)
+		}
+	}
+}